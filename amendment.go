@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// vestingSegment is the stretch of a grant governed by one schedule. The
+// segment's employee has the segment's start date, schedule and the units
+// that had not vested when it began.
+type vestingSegment struct {
+	employee  Employee
+	from      time.Time
+	baseUnits int
+	amended   bool
+}
+
+// AmendSchedule adds an amendment to the employee's grant. From
+// effectiveDate on, the units that have not vested by then vest under
+// schedule, counted from effectiveDate. Units already vested are kept.
+func (e *Employee) AmendSchedule(effectiveDate time.Time, schedule VestingSchedule) error {
+	amendments := append(append([]ScheduleAmendment(nil), e.Amendments...), ScheduleAmendment{
+		EffectiveDate: effectiveDate,
+		Schedule:      schedule,
+	})
+	if err := ValidateAmendments(amendments); err != nil {
+		return err
+	}
+
+	sort.SliceStable(amendments, func(i, j int) bool {
+		return amendments[i].EffectiveDate.Before(amendments[j].EffectiveDate)
+	})
+	e.Amendments = amendments
+	return nil
+}
+
+// ValidateAmendments ensures every amendment has an effective date and a
+// valid schedule, and that no two take effect on the same date
+func ValidateAmendments(amendments []ScheduleAmendment) error {
+	seen := make(map[time.Time]bool)
+	for _, amendment := range amendments {
+		if amendment.EffectiveDate.IsZero() {
+			return fmt.Errorf("amendment has no effective date")
+		}
+		if err := ValidateSchedule(amendment.Schedule); err != nil {
+			return fmt.Errorf("amendment effective %s: %w", amendment.EffectiveDate.Format("2006-01-02"), err)
+		}
+		date := amendment.EffectiveDate.UTC()
+		if seen[date] {
+			return fmt.Errorf("more than one amendment effective %s", amendment.EffectiveDate.Format("2006-01-02"))
+		}
+		seen[date] = true
+	}
+	return nil
+}
+
+// vestingSegments splits the employee's grant at each amendment. The first
+// segment is the original schedule from the start date. An amendment on or
+// before the start date replaces the original schedule outright, and
+// amendments on or after the termination date have no effect.
+func vestingSegments(employee Employee) []vestingSegment {
+	first := vestingStart(employee)
+	first.Amendments = nil
+	segments := []vestingSegment{{employee: first, from: first.StartDate}}
+
+	amendments := append([]ScheduleAmendment(nil), employee.Amendments...)
+	sort.SliceStable(amendments, func(i, j int) bool {
+		return amendments[i].EffectiveDate.Before(amendments[j].EffectiveDate)
+	})
+
+	for _, amendment := range amendments {
+		schedule := amendment.Schedule
+		if schedule.Rounding == "" {
+			schedule.Rounding = employee.Schedule.Rounding
+			schedule.Remainder = employee.Schedule.Remainder
+		}
+		if schedule.VestDay == "" {
+			schedule.VestDay = employee.Schedule.VestDay
+		}
+		if schedule.BusinessDays == "" {
+			schedule.BusinessDays = employee.Schedule.BusinessDays
+		}
+
+		current := &segments[len(segments)-1]
+		if !amendment.EffectiveDate.After(current.from) {
+			current.employee.Schedule = schedule
+			current.amended = true
+			continue
+		}
+		if terminatedBy(employee, amendment.EffectiveDate) {
+			break
+		}
+
+		vested := current.vestedAt(amendment.EffectiveDate)
+		next := first
+		next.StartDate = amendment.EffectiveDate
+		next.TotalUnits = employee.TotalUnits - vested
+		next.Schedule = schedule
+
+		segments = append(segments, vestingSegment{
+			employee:  next,
+			from:      amendment.EffectiveDate,
+			baseUnits: vested,
+			amended:   true,
+		})
+	}
+
+	return segments
+}
+
+// activeSegment returns the segment in force at date
+func activeSegment(segments []vestingSegment, date time.Time) vestingSegment {
+	active := segments[0]
+	for _, segment := range segments[1:] {
+		if segment.from.After(date) {
+			break
+		}
+		active = segment
+	}
+	return active
+}
+
+// vestedAt returns the units vested under the segment as of date, counting
+// the units vested before it
+func (s vestingSegment) vestedAt(date time.Time) int {
+	months := countMonths(s.employee, vestingCutoff(s.employee, date))
+	return s.baseUnits + vestedForMonths(s.employee, months, nil)
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleAmendments(t *testing.T) {
+	service := NewVestingService()
+	linear := VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"}
+
+	tests := []struct {
+		name         string
+		amendments   []ScheduleAmendment
+		termination  time.Time
+		asOfDate     time.Time
+		vestedUnits  int
+		nextVestDate time.Time
+	}{
+		{
+			name:        "before_amendment_unchanged",
+			amendments:  []ScheduleAmendment{{date(2023, 1, 1), VestingSchedule{CliffMonths: 0, VestingMonths: 12, VestingType: "linear"}}},
+			asOfDate:    date(2022, 12, 1),
+			vestedUnits: 3055, // 23 months under the original terms
+		},
+		{
+			name:        "on_amendment_date",
+			amendments:  []ScheduleAmendment{{date(2023, 1, 1), VestingSchedule{CliffMonths: 0, VestingMonths: 12, VestingType: "linear"}}},
+			asOfDate:    date(2023, 1, 1),
+			vestedUnits: 3333, // 24 months under the original terms
+		},
+		{
+			name:        "accelerated",
+			amendments:  []ScheduleAmendment{{date(2023, 1, 1), VestingSchedule{CliffMonths: 0, VestingMonths: 12, VestingType: "linear"}}},
+			asOfDate:    date(2023, 7, 1),
+			vestedUnits: 3333 + 3333, // 6667 * 6/12 = 3333.5
+		},
+		{
+			name:        "accelerated_complete",
+			amendments:  []ScheduleAmendment{{date(2023, 1, 1), VestingSchedule{CliffMonths: 0, VestingMonths: 12, VestingType: "linear"}}},
+			asOfDate:    date(2024, 6, 1),
+			vestedUnits: 10000,
+		},
+		{
+			name:        "extended",
+			amendments:  []ScheduleAmendment{{date(2023, 1, 1), VestingSchedule{CliffMonths: 0, VestingMonths: 36, VestingType: "linear"}}},
+			asOfDate:    date(2024, 1, 1),
+			vestedUnits: 3333 + 2222, // 6667 * 12/36 = 2222.33
+		},
+		{
+			name: "two_amendments",
+			amendments: []ScheduleAmendment{
+				{date(2024, 1, 1), VestingSchedule{CliffMonths: 0, VestingMonths: 6, VestingType: "linear"}},
+				{date(2023, 1, 1), VestingSchedule{CliffMonths: 0, VestingMonths: 36, VestingType: "linear"}},
+			},
+			asOfDate:    date(2024, 4, 1),
+			vestedUnits: 5555 + 2222, // 4445 * 3/6 = 2222.5
+		},
+		{
+			name:         "new_cliff",
+			amendments:   []ScheduleAmendment{{date(2023, 1, 1), VestingSchedule{CliffMonths: 6, VestingMonths: 12, VestingType: "linear"}}},
+			asOfDate:     date(2023, 3, 1),
+			vestedUnits:  3333,
+			nextVestDate: date(2023, 7, 1),
+		},
+		{
+			name:        "replaced_from_start",
+			amendments:  []ScheduleAmendment{{date(2020, 6, 1), VestingSchedule{CliffMonths: 0, VestingMonths: 40, VestingType: "linear"}}},
+			asOfDate:    date(2021, 5, 1),
+			vestedUnits: 1000, // 4 months of 40
+		},
+		{
+			name:        "after_termination_ignored",
+			amendments:  []ScheduleAmendment{{date(2023, 1, 1), VestingSchedule{CliffMonths: 0, VestingMonths: 12, VestingType: "linear"}}},
+			termination: date(2022, 6, 1),
+			asOfDate:    date(2024, 1, 1),
+			vestedUnits: 1388, // 17 months under the original terms
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			employee := Employee{
+				ID:              "emp001",
+				StartDate:       date(2021, 1, 1),
+				TotalUnits:      10000,
+				Schedule:        linear,
+				Amendments:      tt.amendments,
+				TerminationDate: tt.termination,
+			}
+
+			result, err := service.calculateVesting(employee, tt.asOfDate)
+			if err != nil {
+				t.Fatalf("calculateVesting failed: %v", err)
+			}
+			if result.VestedUnits != tt.vestedUnits {
+				t.Errorf("Expected %d vested units, got %d", tt.vestedUnits, result.VestedUnits)
+			}
+			if !tt.nextVestDate.IsZero() && !result.NextVestDate.Equal(tt.nextVestDate) {
+				t.Errorf("Expected next vest date %s, got %s", tt.nextVestDate.Format("2006-01-02"), result.NextVestDate.Format("2006-01-02"))
+			}
+
+			// Events and timeline points agree with calculateVesting
+			events := vestEvents(employee)
+			from := employee.StartDate
+			points, err := service.Timeline(employee, from, date(2026, 1, 1), TimelineStep{Days: 10})
+			if err != nil {
+				t.Fatalf("Timeline failed: %v", err)
+			}
+			for _, point := range points {
+				expected, _ := service.calculateVesting(employee, point.Date)
+				if point.VestedUnits != expected.VestedUnits {
+					t.Fatalf("Timeline at %s has %d vested units, calculateVesting %d",
+						point.Date.Format("2006-01-02"), point.VestedUnits, expected.VestedUnits)
+				}
+
+				sum := 0
+				for _, event := range eventsBetween(events, from, point.Date) {
+					sum += event.Units
+				}
+				if sum != expected.VestedUnits {
+					t.Fatalf("Events before %s sum to %d, calculateVesting %d",
+						point.Date.Format("2006-01-02"), sum, expected.VestedUnits)
+				}
+			}
+		})
+	}
+}
+
+func TestAmendSchedule(t *testing.T) {
+	employee := Employee{
+		ID:         "emp001",
+		StartDate:  date(2021, 1, 1),
+		TotalUnits: 10000,
+		Schedule:   VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"},
+	}
+	original := employee.Schedule
+
+	if err := employee.AmendSchedule(date(2023, 1, 1), VestingSchedule{CliffMonths: 0, VestingMonths: 12, VestingType: "linear"}); err != nil {
+		t.Fatalf("AmendSchedule failed: %v", err)
+	}
+	if err := employee.AmendSchedule(date(2022, 1, 1), VestingSchedule{CliffMonths: 0, VestingMonths: 36, VestingType: "linear"}); err != nil {
+		t.Fatalf("AmendSchedule failed: %v", err)
+	}
+	if employee.Schedule != original {
+		t.Error("AmendSchedule overwrote the original schedule")
+	}
+	if len(employee.Amendments) != 2 || !employee.Amendments[0].EffectiveDate.Equal(date(2022, 1, 1)) {
+		t.Errorf("Expected amendments in date order, got %+v", employee.Amendments)
+	}
+
+	invalid := []struct {
+		name     string
+		date     time.Time
+		schedule VestingSchedule
+	}{
+		{"invalid_schedule", date(2024, 1, 1), VestingSchedule{CliffMonths: 12, VestingMonths: 12, VestingType: "linear"}},
+		{"invalid_type", date(2024, 1, 1), VestingSchedule{CliffMonths: 0, VestingMonths: 12, VestingType: "cliff"}},
+		{"no_date", time.Time{}, VestingSchedule{CliffMonths: 0, VestingMonths: 12, VestingType: "linear"}},
+		{"same_date", date(2023, 1, 1), VestingSchedule{CliffMonths: 0, VestingMonths: 24, VestingType: "linear"}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := employee.AmendSchedule(tt.date, tt.schedule); err == nil {
+				t.Error("Expected amendment to be rejected")
+			}
+			if len(employee.Amendments) != 2 {
+				t.Error("Rejected amendment was added")
+			}
+		})
+	}
+
+	// Amendments set directly are validated when calculating
+	employee.Amendments = append(employee.Amendments, ScheduleAmendment{EffectiveDate: date(2024, 1, 1)})
+	if _, err := NewVestingService().calculateVesting(employee, date(2024, 6, 1)); err == nil {
+		t.Error("Expected calculateVesting to reject an invalid amendment")
+	}
+}
+
+func TestExplainAmendment(t *testing.T) {
+	employee := Employee{
+		ID:         "emp001",
+		StartDate:  date(2021, 1, 1),
+		TotalUnits: 10000,
+		Schedule:   VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"},
+		Amendments: []ScheduleAmendment{{date(2023, 1, 1), VestingSchedule{CliffMonths: 0, VestingMonths: 12, VestingType: "linear"}}},
+	}
+
+	_, explanation, err := NewVestingService().ExplainVesting(employee, date(2023, 7, 1))
+	if err != nil {
+		t.Fatalf("ExplainVesting failed: %v", err)
+	}
+	if !explanation.AmendedOn.Equal(date(2023, 1, 1)) || explanation.UnitsBeforeAmendment != 3333 {
+		t.Errorf("Expected amendment on 2023-01-01 with 3333 units before, got %s and %d",
+			explanation.AmendedOn.Format("2006-01-02"), explanation.UnitsBeforeAmendment)
+	}
+	if explanation.MonthsEmployed != 6 || explanation.VestingMonths != 12 || explanation.VestedUnits != 6666 {
+		t.Errorf("Unexpected explanation: %+v", explanation)
+	}
+}
+
+func TestLifecycleScheduleAmendment(t *testing.T) {
+	service := NewVestingService()
+	store := NewMemoryEventStore()
+
+	for _, event := range []LifecycleEvent{
+		{Type: EventHired, EmployeeID: "emp001", EffectiveDate: date(2021, 1, 1)},
+		{Type: EventGrantIssued, EmployeeID: "emp001", Units: 10000,
+			Schedule: VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"}, EffectiveDate: date(2021, 1, 1)},
+		{Type: EventScheduleAmended, EmployeeID: "emp001",
+			Schedule: VestingSchedule{CliffMonths: 0, VestingMonths: 12, VestingType: "linear"}, EffectiveDate: date(2023, 1, 1)},
+	} {
+		if _, err := service.RecordEvent(store, event); err != nil {
+			t.Fatalf("RecordEvent failed: %v", err)
+		}
+	}
+
+	for _, tt := range []struct {
+		asOfDate    time.Time
+		vestedUnits int
+	}{
+		{date(2022, 12, 1), 3055},
+		{date(2023, 7, 1), 6666},
+	} {
+		state, err := service.ReplayEmployee(store, "", "emp001", tt.asOfDate)
+		if err != nil {
+			t.Fatalf("ReplayEmployee failed: %v", err)
+		}
+		if state.Result.VestedUnits != tt.vestedUnits {
+			t.Errorf("As of %s: expected %d vested units, got %d", tt.asOfDate.Format("2006-01-02"), tt.vestedUnits, state.Result.VestedUnits)
+		}
+	}
+}
+
+func TestMonthZeroVestEvent(t *testing.T) {
+	service := NewVestingService()
+	backloaded := VestingSchedule{CliffMonths: 0, VestingMonths: 48, VestingType: "backloaded"}
+
+	// A backloaded schedule without a cliff vests the first year's 10% at
+	// month 0
+	grant := Employee{ID: "emp001", StartDate: date(2021, 1, 1), TotalUnits: 10000, Schedule: backloaded}
+	result, err := service.calculateVesting(grant, date(2021, 1, 1))
+	if err != nil {
+		t.Fatalf("calculateVesting failed: %v", err)
+	}
+	if result.VestedUnits != 1000 {
+		t.Errorf("Expected 1000 units vested on the start date, got %d", result.VestedUnits)
+	}
+
+	amended := Employee{ID: "emp002", StartDate: date(2021, 1, 1), TotalUnits: 10000,
+		Schedule:   VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"},
+		Amendments: []ScheduleAmendment{{date(2023, 1, 1), backloaded}}}
+
+	tests := []struct {
+		name      string
+		employee  Employee
+		start     time.Time
+		baseUnits int
+		units     int
+	}{
+		// Month 0 and month 1 vest on the start date: 10% + 10%/12
+		{"grant", grant, date(2021, 1, 1), 0, 1083},
+		// 3333 units vested under the original terms, then 6667 * 13/120
+		{"amendment", amended, date(2023, 1, 1), 3333, 722},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := vestEvents(tt.employee)
+			total := 0
+			var startEvent *VestEvent
+			for i, event := range events {
+				total += event.Units
+				if total != event.CumulativeUnits {
+					t.Fatalf("Event on %s has %d cumulative units, but the events add up to %d",
+						event.Date.Format("2006-01-02"), event.CumulativeUnits, total)
+				}
+				if event.Date.Equal(tt.start) {
+					startEvent = &events[i]
+				}
+			}
+			if startEvent == nil || startEvent.Units != tt.units || startEvent.CumulativeUnits != tt.baseUnits+tt.units {
+				t.Fatalf("Expected %d units to vest on %s, got %+v", tt.units, tt.start.Format("2006-01-02"), startEvent)
+			}
+		})
+	}
+
+	// The units vested on the amendment date are reported in the period
+	// that contains it
+	report, err := service.VestedBetween([]Employee{amended}, date(2022, 12, 1), date(2023, 2, 1))
+	if err != nil {
+		t.Fatalf("VestedBetween failed: %v", err)
+	}
+	period := report.Employees[0]
+	sum, amendmentUnits := 0, 0
+	for _, event := range period.Events {
+		sum += event.Units
+		if event.Date.Equal(date(2023, 1, 1)) {
+			amendmentUnits = event.Units
+		}
+	}
+	if period.VestedUnits != sum || amendmentUnits != 722 {
+		t.Errorf("Expected the period's %d vested units to equal the sum of its events, %d, with 722 on 2023-01-01, got %d",
+			period.VestedUnits, sum, amendmentUnits)
+	}
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Roles a principal can hold
+const (
+	RoleEmployee = "employee"
+	RoleManager  = "manager"
+	RoleAdmin    = "admin"
+)
+
+// Operations checked by AuthorizedService
+const (
+	OpReadResult = "read_result"
+	OpReadBatch  = "read_batch"
+	OpRunBatch   = "run_batch"
+	OpClearCache = "clear_cache"
+)
+
+// ErrAccessDenied is returned, wrapped, for every rejected request
+var ErrAccessDenied = errors.New("access denied")
+
+// Principal is the caller of an AuthorizedService operation. Subject is the
+// caller's own employee ID. Every principal expires.
+type Principal struct {
+	Subject  string    `json:"sub"`
+	Role     string    `json:"role"`
+	TenantID string    `json:"tenant,omitempty"`
+	Expires  time.Time `json:"exp"`
+}
+
+// IdentityResolver turns the credential presented with a request into the
+// principal making it
+type IdentityResolver interface {
+	Resolve(token string) (Principal, error)
+}
+
+// Directory is the trusted source of reporting lines. Manager relationships
+// are never taken from the employees a caller sends.
+type Directory interface {
+	// ManagerOf returns the employee ID of an employee's direct manager
+	ManagerOf(tenantID, employeeID string) (string, bool)
+}
+
+// MemoryDirectory keeps reporting lines in memory
+type MemoryDirectory struct {
+	mu       sync.Mutex
+	managers map[cacheKey]string
+}
+
+func NewMemoryDirectory() *MemoryDirectory {
+	return &MemoryDirectory{managers: make(map[cacheKey]string)}
+}
+
+// SetManager records managerID as the direct manager of an employee
+func (d *MemoryDirectory) SetManager(tenantID, employeeID, managerID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.managers[cacheKey{TenantID: tenantID, EmployeeID: employeeID}] = managerID
+}
+
+// ManagerOf returns the employee's direct manager
+func (d *MemoryDirectory) ManagerOf(tenantID, employeeID string) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	managerID, exists := d.managers[cacheKey{TenantID: tenantID, EmployeeID: employeeID}]
+	return managerID, exists
+}
+
+// AccessDenial is a rejected request
+type AccessDenial struct {
+	Time       time.Time `json:"time"`
+	Subject    string    `json:"subject,omitempty"`
+	Role       string    `json:"role,omitempty"`
+	TenantID   string    `json:"tenant_id,omitempty"`
+	Operation  string    `json:"operation"`
+	EmployeeID string    `json:"employee_id,omitempty"`
+	Reason     string    `json:"reason"`
+}
+
+// DenialLog stores rejected requests
+type DenialLog interface {
+	Record(denial AccessDenial) error
+}
+
+// MemoryDenialLog keeps rejected requests in memory
+type MemoryDenialLog struct {
+	mu      sync.Mutex
+	denials []AccessDenial
+}
+
+// Record appends a denial to the log
+func (l *MemoryDenialLog) Record(denial AccessDenial) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.denials = append(l.denials, denial)
+	return nil
+}
+
+// Denials returns the recorded denials in the order they happened
+func (l *MemoryDenialLog) Denials() []AccessDenial {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]AccessDenial(nil), l.denials...)
+}
+
+// HMACTokenResolver verifies tokens signed with a shared secret without
+// calling out to an identity provider. A token is the base64url JSON
+// principal and its base64url HMAC-SHA256, joined by a dot. The secret must
+// not be empty.
+type HMACTokenResolver struct {
+	Secret []byte
+
+	now func() time.Time
+}
+
+func NewHMACTokenResolver(secret []byte) *HMACTokenResolver {
+	return &HMACTokenResolver{Secret: secret, now: time.Now}
+}
+
+// Issue returns a signed token for the principal, which must have an expiry
+func (r *HMACTokenResolver) Issue(principal Principal) (string, error) {
+	if len(r.Secret) == 0 {
+		return "", fmt.Errorf("token secret is empty")
+	}
+	if err := validateRole(principal.Role); err != nil {
+		return "", err
+	}
+	if principal.Expires.IsZero() {
+		return "", fmt.Errorf("token has no expiry")
+	}
+	claims, err := json.Marshal(principal)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(claims)
+	return payload + "." + r.sign(payload), nil
+}
+
+// Resolve verifies a token's signature and expiry and returns its principal
+func (r *HMACTokenResolver) Resolve(token string) (Principal, error) {
+	if len(r.Secret) == 0 {
+		return Principal{}, fmt.Errorf("token secret is empty")
+	}
+	payload, signature, found := strings.Cut(token, ".")
+	if !found {
+		return Principal{}, fmt.Errorf("malformed token")
+	}
+	if !hmac.Equal([]byte(signature), []byte(r.sign(payload))) {
+		return Principal{}, fmt.Errorf("invalid token signature")
+	}
+
+	claims, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return Principal{}, fmt.Errorf("malformed token: %w", err)
+	}
+	var principal Principal
+	if err := json.Unmarshal(claims, &principal); err != nil {
+		return Principal{}, fmt.Errorf("malformed token: %w", err)
+	}
+
+	if principal.Subject == "" {
+		return Principal{}, fmt.Errorf("token has no subject")
+	}
+	if err := validateRole(principal.Role); err != nil {
+		return Principal{}, err
+	}
+	if principal.Expires.IsZero() {
+		return Principal{}, fmt.Errorf("token has no expiry")
+	}
+	now := time.Now
+	if r.now != nil {
+		now = r.now
+	}
+	if !now().Before(principal.Expires) {
+		return Principal{}, fmt.Errorf("token expired at %s", principal.Expires.Format(time.RFC3339))
+	}
+	return principal, nil
+}
+
+func (r *HMACTokenResolver) sign(payload string) string {
+	mac := hmac.New(sha256.New, r.Secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func validateRole(role string) error {
+	if role != RoleEmployee && role != RoleManager && role != RoleAdmin {
+		return fmt.Errorf("invalid role: %s", role)
+	}
+	return nil
+}
+
+// AuthorizedService wraps a VestingService so every operation is made on
+// behalf of the principal a token resolves to, within the principal's
+// tenant. Employees may only see their own results, managers also those of
+// their direct reports in Directory, and admins everything. Only admins may
+// calculate results, since a batch writes the cache every caller reads.
+// Every denial is written to Denials, when set, and to the service's logger.
+type AuthorizedService struct {
+	Service   *VestingService
+	Resolver  IdentityResolver
+	Directory Directory
+	Denials   DenialLog
+}
+
+func NewAuthorizedService(vs *VestingService, resolver IdentityResolver, directory Directory, denials DenialLog) *AuthorizedService {
+	return &AuthorizedService{Service: vs, Resolver: resolver, Directory: directory, Denials: denials}
+}
+
+// GetResult retrieves a cached result the caller may see
+func (as *AuthorizedService) GetResult(token, employeeID string) (VestingResult, bool, error) {
+	principal, err := as.authenticate(token, OpReadResult)
+	if err != nil {
+		return VestingResult{}, false, err
+	}
+	if err := as.authorizeRead(principal, OpReadResult, employeeID); err != nil {
+		return VestingResult{}, false, err
+	}
+
+	result, exists := as.Service.getResult(principal.TenantID, employeeID)
+	return result, exists, nil
+}
+
+// GetBatchResults returns cached results for a list of employee IDs. The
+// whole request is denied if the caller may not see any one of them.
+func (as *AuthorizedService) GetBatchResults(token string, employeeIDs []string) (map[string]VestingResult, error) {
+	principal, err := as.authenticate(token, OpReadBatch)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range employeeIDs {
+		if err := as.authorizeRead(principal, OpReadBatch, id); err != nil {
+			return nil, err
+		}
+	}
+
+	return as.Service.getBatchResults(principal.TenantID, employeeIDs)
+}
+
+// ProcessBatch calculates vesting and caches the results. Only admins may
+// run a batch: the employees are taken as sent, so a batch from anyone else
+// could overwrite the grants other callers read.
+func (as *AuthorizedService) ProcessBatch(token string, employees []Employee, asOfDate time.Time) error {
+	principal, err := as.authenticate(token, OpRunBatch)
+	if err != nil {
+		return err
+	}
+	if principal.Role != RoleAdmin {
+		return as.deny(principal, OpRunBatch, "", "running a batch requires the admin role")
+	}
+
+	return as.Service.processBatch(principal.TenantID, employees, asOfDate)
+}
+
+// ClearCache clears the cached results of the caller's tenant. Only admins
+// may clear the cache.
+func (as *AuthorizedService) ClearCache(token string) error {
+	principal, err := as.authenticate(token, OpClearCache)
+	if err != nil {
+		return err
+	}
+	if principal.Role != RoleAdmin {
+		return as.deny(principal, OpClearCache, "", "clearing the cache requires the admin role")
+	}
+
+	as.Service.Tenant(principal.TenantID).ClearCache()
+	return nil
+}
+
+// authenticate resolves the token, recording a denial if it is rejected
+func (as *AuthorizedService) authenticate(token, operation string) (Principal, error) {
+	principal, err := as.Resolver.Resolve(token)
+	if err != nil {
+		return Principal{}, as.deny(Principal{}, operation, "", err.Error())
+	}
+	return principal, nil
+}
+
+// authorizeRead checks that the principal may see an employee's cached
+// result. Managers are matched against the reporting lines in the
+// directory.
+func (as *AuthorizedService) authorizeRead(principal Principal, operation, employeeID string) error {
+	if principal.Role == RoleAdmin || principal.Subject == employeeID {
+		return nil
+	}
+	if principal.Role == RoleManager && as.Directory != nil {
+		managerID, exists := as.Directory.ManagerOf(principal.TenantID, employeeID)
+		if exists && managerID == principal.Subject {
+			return nil
+		}
+	}
+	return as.deny(principal, operation, employeeID, "employee is not the caller or a direct report")
+}
+
+// deny records a denial and returns the error for it
+func (as *AuthorizedService) deny(principal Principal, operation, employeeID, reason string) error {
+	denial := AccessDenial{
+		Time:       time.Now().UTC(),
+		Subject:    principal.Subject,
+		Role:       principal.Role,
+		TenantID:   principal.TenantID,
+		Operation:  operation,
+		EmployeeID: employeeID,
+		Reason:     reason,
+	}
+
+	as.Service.log().Warn("access denied",
+		slog.String("subject", denial.Subject),
+		slog.String("role", denial.Role),
+		slog.String("tenant_id", denial.TenantID),
+		slog.String("operation", denial.Operation),
+		slog.String("employee_id", denial.EmployeeID),
+		slog.String("reason", denial.Reason))
+
+	if as.Denials != nil {
+		if err := as.Denials.Record(denial); err != nil {
+			return fmt.Errorf("%w: %s (recording denial: %v)", ErrAccessDenied, reason, err)
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrAccessDenied, reason)
+}
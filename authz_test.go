@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHMACTokenResolver(t *testing.T) {
+	resolver := NewHMACTokenResolver([]byte("secret"))
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	resolver.now = func() time.Time { return now }
+
+	token, err := resolver.Issue(Principal{Subject: "emp001", Role: RoleManager, TenantID: "acme", Expires: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+
+	principal, err := resolver.Resolve(token)
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if principal.Subject != "emp001" || principal.Role != RoleManager || principal.TenantID != "acme" {
+		t.Errorf("Unexpected principal: %+v", principal)
+	}
+
+	payload, _, _ := strings.Cut(token, ".")
+	forged, _ := NewHMACTokenResolver([]byte("other")).Issue(Principal{Subject: "emp001", Role: RoleAdmin, Expires: now.Add(time.Hour)})
+	_, forgedSignature, _ := strings.Cut(forged, ".")
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"no_signature", payload},
+		{"wrong_secret", forged},
+		{"swapped_signature", payload + "." + forgedSignature},
+		{"tampered_payload", strings.Replace(token, payload, payload[:len(payload)-2]+"AA", 1)},
+		{"empty", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := resolver.Resolve(tt.token); err == nil {
+				t.Error("Expected token to be rejected")
+			}
+		})
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, err := resolver.Resolve(token); err == nil {
+		t.Error("Expected expired token to be rejected")
+	}
+
+	if _, err := resolver.Issue(Principal{Subject: "emp001", Role: "root", Expires: now.Add(time.Hour)}); err == nil {
+		t.Error("Expected error issuing a token with an invalid role")
+	}
+	if _, err := resolver.Issue(Principal{Subject: "emp001", Role: RoleAdmin}); err == nil {
+		t.Error("Expected error issuing a token with no expiry")
+	}
+
+	// A correctly signed token without exp never resolves
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"emp001","role":"admin"}`))
+	if _, err := resolver.Resolve(claims + "." + resolver.sign(claims)); err == nil {
+		t.Error("Expected a token with no expiry to be rejected")
+	}
+
+	// A resolver literal checks expiry against the current time
+	literal := &HMACTokenResolver{Secret: []byte("secret")}
+	current, err := literal.Issue(Principal{Subject: "emp001", Role: RoleAdmin, Expires: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	if _, err := literal.Resolve(current); err != nil {
+		t.Errorf("Resolve failed: %v", err)
+	}
+	if _, err := literal.Resolve(token); err == nil {
+		t.Error("Expected a token expired by the current time to be rejected")
+	}
+
+	// Tokens are never signed or verified with an empty secret
+	unkeyed := NewHMACTokenResolver(nil)
+	if _, err := unkeyed.Issue(Principal{Subject: "emp001", Role: RoleAdmin, Expires: now.Add(time.Hour)}); err == nil {
+		t.Error("Expected error issuing a token with an empty secret")
+	}
+	if _, err := unkeyed.Resolve(payload + "." + unkeyed.sign(payload)); err == nil {
+		t.Error("Expected a token signed with an empty secret to be rejected")
+	}
+}
+
+func TestAuthorizedService(t *testing.T) {
+	resolver := NewHMACTokenResolver([]byte("secret"))
+	denials := &MemoryDenialLog{}
+	directory := NewMemoryDirectory()
+	directory.SetManager("", "emp001", "mgr")
+	service := NewAuthorizedService(NewVestingService(), resolver, directory, denials)
+
+	issue := func(subject, role string) string {
+		token, err := resolver.Issue(Principal{Subject: subject, Role: role, Expires: time.Now().Add(time.Hour)})
+		if err != nil {
+			t.Fatalf("Issue failed: %v", err)
+		}
+		return token
+	}
+	admin := issue("adm", RoleAdmin)
+	manager := issue("mgr", RoleManager)
+	report := issue("emp001", RoleEmployee)
+	peer := issue("emp002", RoleEmployee)
+
+	schedule := VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"}
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	employees := []Employee{
+		{ID: "mgr", StartDate: start, TotalUnits: 20000, Schedule: schedule},
+		{ID: "emp001", StartDate: start, TotalUnits: 10000, Schedule: schedule},
+		{ID: "emp002", StartDate: start, TotalUnits: 10000, Schedule: schedule},
+	}
+	asOfDate := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Only the admin may run a batch, since it writes the shared cache
+	if err := service.ProcessBatch(manager, employees[:2], asOfDate); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Expected manager batch to be denied, got %v", err)
+	}
+	if err := service.ProcessBatch(report, employees[1:2], asOfDate); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Expected employee batch to be denied, got %v", err)
+	}
+	if err := service.ProcessBatch(admin, employees, asOfDate); err != nil {
+		t.Fatalf("Admin ProcessBatch failed: %v", err)
+	}
+
+	reads := []struct {
+		name       string
+		token      string
+		employeeID string
+		allowed    bool
+	}{
+		{"employee_own", report, "emp001", true},
+		{"employee_other", report, "emp002", false},
+		{"employee_manager", report, "mgr", false},
+		{"manager_own", manager, "mgr", true},
+		{"manager_report", manager, "emp001", true},
+		{"manager_non_report", manager, "emp002", false},
+		{"admin_any", admin, "emp002", true},
+		{"invalid_token", "garbage", "emp001", false},
+	}
+
+	for _, tt := range reads {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(denials.Denials())
+			result, exists, err := service.GetResult(tt.token, tt.employeeID)
+
+			if tt.allowed {
+				if err != nil || !exists || result.EmployeeID != tt.employeeID {
+					t.Errorf("Expected result for %s, got %+v, %v, %v", tt.employeeID, result, exists, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrAccessDenied) {
+				t.Errorf("Expected access denied, got %v", err)
+			}
+			if exists {
+				t.Error("Denied read returned a result")
+			}
+			if len(denials.Denials()) != before+1 {
+				t.Error("Expected the denial to be logged")
+			}
+		})
+	}
+
+	if _, err := service.GetBatchResults(manager, []string{"mgr", "emp001"}); err != nil {
+		t.Errorf("Expected manager batch read to succeed, got %v", err)
+	}
+	if _, err := service.GetBatchResults(peer, []string{"emp002", "emp001"}); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Expected batch read including another employee to be denied, got %v", err)
+	}
+
+	last := denials.Denials()[len(denials.Denials())-1]
+	if last.Subject != "emp002" || last.Operation != OpReadBatch || last.EmployeeID != "emp001" {
+		t.Errorf("Unexpected denial record: %+v", last)
+	}
+
+	if err := service.ClearCache(manager); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Expected manager cache clear to be denied, got %v", err)
+	}
+	if _, exists, _ := service.GetResult(admin, "emp001"); !exists {
+		t.Error("Denied cache clear removed results")
+	}
+	if err := service.ClearCache(admin); err != nil {
+		t.Errorf("Admin ClearCache failed: %v", err)
+	}
+	if _, exists, _ := service.GetResult(admin, "emp001"); exists {
+		t.Error("Results survived the admin cache clear")
+	}
+}
+
+func TestAuthorizedServiceTenant(t *testing.T) {
+	resolver := NewHMACTokenResolver([]byte("secret"))
+	vs := NewVestingService()
+	service := NewAuthorizedService(vs, resolver, NewMemoryDirectory(), &MemoryDenialLog{})
+
+	employee := Employee{
+		ID:         "emp001",
+		StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		TotalUnits: 10000,
+		Schedule:   VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"},
+	}
+	asOfDate := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := vs.Tenant("acme").ProcessBatch([]Employee{employee}, asOfDate); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+
+	expires := time.Now().Add(time.Hour)
+	otherAdmin, _ := resolver.Issue(Principal{Subject: "adm", Role: RoleAdmin, TenantID: "globex", Expires: expires})
+	if _, exists, err := service.GetResult(otherAdmin, "emp001"); err != nil || exists {
+		t.Errorf("Admin of another tenant read the result: %v, %v", exists, err)
+	}
+
+	acmeAdmin, _ := resolver.Issue(Principal{Subject: "adm", Role: RoleAdmin, TenantID: "acme", Expires: expires})
+	if _, exists, err := service.GetResult(acmeAdmin, "emp001"); err != nil || !exists {
+		t.Errorf("Expected the tenant's admin to read the result: %v, %v", exists, err)
+	}
+}
+
+func TestAuthorizedServiceForgedRecords(t *testing.T) {
+	resolver := NewHMACTokenResolver([]byte("secret"))
+	directory := NewMemoryDirectory()
+	directory.SetManager("", "emp001", "mgr")
+	service := NewAuthorizedService(NewVestingService(), resolver, directory, &MemoryDenialLog{})
+
+	issue := func(subject, role string) string {
+		token, err := resolver.Issue(Principal{Subject: subject, Role: role, Expires: time.Now().Add(time.Hour)})
+		if err != nil {
+			t.Fatalf("Issue failed: %v", err)
+		}
+		return token
+	}
+	admin := issue("adm", RoleAdmin)
+	manager := issue("mgr", RoleManager)
+	employee := issue("emp001", RoleEmployee)
+
+	schedule := VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"}
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	asOfDate := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := service.ProcessBatch(admin, []Employee{
+		{ID: "emp001", StartDate: start, TotalUnits: 10000, Schedule: schedule},
+		{ID: "emp002", StartDate: start, TotalUnits: 10000, Schedule: schedule},
+	}, asOfDate); err != nil {
+		t.Fatalf("Admin ProcessBatch failed: %v", err)
+	}
+
+	// A manager cannot overwrite the record of someone else's report
+	forged := Employee{ID: "emp002", StartDate: start, TotalUnits: 1, Schedule: schedule}
+	if err := service.ProcessBatch(manager, []Employee{forged}, asOfDate); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Expected the forged batch to be denied, got %v", err)
+	}
+	if _, _, err := service.GetResult(manager, "emp002"); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Expected the manager to be denied a report not in the directory, got %v", err)
+	}
+	if _, _, err := service.GetResult(manager, "emp001"); err != nil {
+		t.Errorf("Expected the manager to read a report in the directory, got %v", err)
+	}
+
+	// An employee cannot inflate their own grant
+	inflated := Employee{ID: "emp001", StartDate: start, TotalUnits: 9999999, Schedule: schedule}
+	if err := service.ProcessBatch(employee, []Employee{inflated}, asOfDate); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Expected the employee batch to be denied, got %v", err)
+	}
+
+	for _, id := range []string{"emp001", "emp002"} {
+		result, _, err := service.GetResult(admin, id)
+		if err != nil || result.VestedUnits+result.UnvestedUnits != 10000 {
+			t.Errorf("Expected %s's cached grant of 10000 units to be unchanged, got %+v (%v)", id, result, err)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// EmployeeVersion is one recorded version of an employee record. It applies
+// from ValidFrom until a later version takes over, as known from RecordedAt
+// on.
+type EmployeeVersion struct {
+	Version    int
+	Employee   Employee
+	ValidFrom  time.Time
+	RecordedAt time.Time
+}
+
+// ScheduleVersion is one recorded version of an employee's schedule. It
+// overrides the schedule of the employee record it applies to.
+type ScheduleVersion struct {
+	Version    int
+	TenantID   string
+	EmployeeID string
+	Schedule   VestingSchedule
+	ValidFrom  time.Time
+	RecordedAt time.Time
+}
+
+// VersionedStore keeps every version of employee and schedule records ever
+// recorded. Nothing is overwritten: a correction is a new version with the
+// same ValidFrom, so queries known before it still see the old value.
+type VersionedStore struct {
+	mu        sync.Mutex
+	employees []EmployeeVersion
+	schedules []ScheduleVersion
+	now       func() time.Time
+}
+
+func NewVersionedStore() *VersionedStore {
+	return &VersionedStore{now: time.Now}
+}
+
+// PutEmployee records a version of an employee valid from validFrom
+func (s *VersionedStore) PutEmployee(employee Employee, validFrom time.Time) EmployeeVersion {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	version := EmployeeVersion{
+		Version:    len(s.employees) + 1,
+		Employee:   employee,
+		ValidFrom:  validFrom,
+		RecordedAt: s.now().UTC(),
+	}
+	s.employees = append(s.employees, version)
+	return version
+}
+
+// PutSchedule records a version of an employee's schedule valid from
+// validFrom
+func (s *VersionedStore) PutSchedule(tenantID, employeeID string, schedule VestingSchedule, validFrom time.Time) (ScheduleVersion, error) {
+	if err := ValidateSchedule(schedule); err != nil {
+		return ScheduleVersion{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	version := ScheduleVersion{
+		Version:    len(s.schedules) + 1,
+		TenantID:   tenantID,
+		EmployeeID: employeeID,
+		Schedule:   schedule,
+		ValidFrom:  validFrom,
+		RecordedAt: s.now().UTC(),
+	}
+	s.schedules = append(s.schedules, version)
+	return version, nil
+}
+
+// Employee returns the employee as it was valid on asOfDate, according to
+// what had been recorded by knownAt
+func (s *VersionedStore) Employee(tenantID, employeeID string, asOfDate, knownAt time.Time) (Employee, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.employeeAt(tenantID, employeeID, asOfDate, knownAt)
+}
+
+// Employees returns every employee of a tenant as valid on asOfDate,
+// according to what had been recorded by knownAt, ordered by ID
+func (s *VersionedStore) Employees(tenantID string, asOfDate, knownAt time.Time) []Employee {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var employees []Employee
+	for _, version := range s.employees {
+		id := version.Employee.ID
+		if version.Employee.TenantID != tenantID || seen[id] {
+			continue
+		}
+		seen[id] = true
+		if employee, exists := s.employeeAt(tenantID, id, asOfDate, knownAt); exists {
+			employees = append(employees, employee)
+		}
+	}
+
+	sort.Slice(employees, func(i, j int) bool {
+		return employees[i].ID < employees[j].ID
+	})
+	return employees
+}
+
+// employeeAt does the work of Employee with the lock held. Of the versions
+// known by knownAt, the one with the latest ValidFrom on or before asOfDate
+// applies, and among versions with the same ValidFrom the last recorded.
+func (s *VersionedStore) employeeAt(tenantID, employeeID string, asOfDate, knownAt time.Time) (Employee, bool) {
+	var current *EmployeeVersion
+	for i := range s.employees {
+		version := &s.employees[i]
+		if version.Employee.TenantID != tenantID || version.Employee.ID != employeeID {
+			continue
+		}
+		if version.RecordedAt.After(knownAt) || version.ValidFrom.After(asOfDate) {
+			continue
+		}
+		if current == nil || !version.ValidFrom.Before(current.ValidFrom) {
+			current = version
+		}
+	}
+	if current == nil {
+		return Employee{}, false
+	}
+	employee := current.Employee
+
+	var schedule *ScheduleVersion
+	for i := range s.schedules {
+		version := &s.schedules[i]
+		if version.TenantID != tenantID || version.EmployeeID != employeeID {
+			continue
+		}
+		if version.RecordedAt.After(knownAt) || version.ValidFrom.After(asOfDate) {
+			continue
+		}
+		if schedule == nil || !version.ValidFrom.Before(schedule.ValidFrom) {
+			schedule = version
+		}
+	}
+	if schedule != nil {
+		employee.Schedule = schedule.Schedule
+	}
+	return employee, true
+}
+
+// CalculateAsKnown calculates vesting as of asOfDate from the employee
+// record and the stock splits as they were known at knownAt. With knownAt
+// set to the time a report was run, the report's result is reproduced
+// exactly, regardless of corrections and splits recorded since.
+func (vs *VestingService) CalculateAsKnown(store *VersionedStore, tenantID, employeeID string, asOfDate, knownAt time.Time) (VestingResult, error) {
+	employee, exists := store.Employee(tenantID, employeeID, asOfDate, knownAt)
+	if !exists {
+		return VestingResult{}, fmt.Errorf("no record for employee %s as of %s known at %s",
+			employeeID, asOfDate.Format("2006-01-02"), knownAt.Format(time.RFC3339))
+	}
+	return vs.calculate(employee, asOfDate, knownAt, nil)
+}
+
+// ReportAsKnown calculates vesting as of asOfDate for every employee of a
+// tenant as they and the stock splits were known at knownAt, ordered by
+// employee ID
+func (vs *VestingService) ReportAsKnown(store *VersionedStore, tenantID string, asOfDate, knownAt time.Time) ([]VestingResult, error) {
+	employees := store.Employees(tenantID, asOfDate, knownAt)
+	results := make([]VestingResult, 0, len(employees))
+	for _, employee := range employees {
+		result, err := vs.calculate(employee, asOfDate, knownAt, nil)
+		if err != nil {
+			return nil, fmt.Errorf("employee %s: %w", employee.ID, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// ReplayEmployeeAsKnown is ReplayEmployee using only the events that had
+// been recorded by knownAt
+func (vs *VestingService) ReplayEmployeeAsKnown(store EventStore, tenantID, employeeID string, asOfDate, knownAt time.Time) (EmployeeState, error) {
+	events, err := store.Events(tenantID, employeeID)
+	if err != nil {
+		return EmployeeState{}, err
+	}
+
+	var known []LifecycleEvent
+	for _, event := range events {
+		if !event.RecordedAt.After(knownAt) {
+			known = append(known, event)
+		}
+	}
+	if len(known) == 0 {
+		return EmployeeState{}, fmt.Errorf("no events for employee %s known at %s", employeeID, knownAt.Format(time.RFC3339))
+	}
+	return vs.replay(known, asOfDate)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalculateAsKnown(t *testing.T) {
+	service := NewVestingService()
+	store := NewVersionedStore()
+
+	var now time.Time
+	store.now = func() time.Time { return now }
+
+	linear := VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"}
+
+	now = date(2023, 1, 5)
+	store.PutEmployee(Employee{ID: "emp001", StartDate: date(2021, 1, 1), TotalUnits: 10000, Schedule: linear}, date(2021, 1, 1))
+	store.PutEmployee(Employee{ID: "emp002", StartDate: date(2021, 6, 1), TotalUnits: 10000, Schedule: linear}, date(2021, 6, 1))
+
+	now = date(2023, 2, 1)
+	store.PutEmployee(Employee{ID: "emp003", StartDate: date(2023, 5, 1), TotalUnits: 10000, Schedule: linear}, date(2023, 5, 1))
+
+	// The start date is corrected after the quarter-end report
+	now = date(2023, 4, 10)
+	store.PutEmployee(Employee{ID: "emp001", StartDate: date(2021, 2, 1), TotalUnits: 10000, Schedule: linear}, date(2021, 1, 1))
+
+	// A new schedule takes effect in the next quarter
+	now = date(2023, 4, 20)
+	if _, err := store.PutSchedule("", "emp001", VestingSchedule{CliffMonths: 0, VestingMonths: 24, VestingType: "linear"}, date(2023, 4, 1)); err != nil {
+		t.Fatalf("PutSchedule failed: %v", err)
+	}
+	if _, err := store.PutSchedule("", "emp001", VestingSchedule{CliffMonths: 24, VestingMonths: 24, VestingType: "linear"}, date(2023, 4, 1)); err == nil {
+		t.Error("Expected an invalid schedule to be rejected")
+	}
+
+	quarterEnd := date(2023, 3, 31)
+	reportedAt := time.Date(2023, 3, 31, 23, 59, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		asOfDate    time.Time
+		knownAt     time.Time
+		vestedUnits int
+	}{
+		// 27 months from 2021-01-01, as reported at quarter end
+		{"as_reported", quarterEnd, reportedAt, 4166},
+		// 26 months from the corrected start date
+		{"corrected", quarterEnd, date(2023, 4, 10), 3888},
+		// 28 months from the corrected start, before the schedule change was known
+		{"before_schedule_known", date(2023, 6, 1), date(2023, 4, 15), 4444},
+		{"after_schedule_known", date(2023, 6, 1), date(2023, 5, 1), 10000},
+		// The new schedule does not apply before it takes effect
+		{"schedule_not_yet_effective", quarterEnd, date(2023, 5, 1), 3888},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := service.CalculateAsKnown(store, "", "emp001", tt.asOfDate, tt.knownAt)
+			if err != nil {
+				t.Fatalf("CalculateAsKnown failed: %v", err)
+			}
+			if result.VestedUnits != tt.vestedUnits {
+				t.Errorf("Expected %d vested units, got %d", tt.vestedUnits, result.VestedUnits)
+			}
+		})
+	}
+
+	if _, err := service.CalculateAsKnown(store, "", "emp001", quarterEnd, date(2023, 1, 1)); err == nil {
+		t.Error("Expected error for an employee not yet recorded")
+	}
+	if _, err := service.CalculateAsKnown(store, "acme", "emp001", quarterEnd, reportedAt); err == nil {
+		t.Error("Expected records of another tenant to be ignored")
+	}
+
+	// The quarter-end report is reproduced exactly, without emp003, who
+	// was not yet valid
+	report, err := service.ReportAsKnown(store, "", quarterEnd, reportedAt)
+	if err != nil {
+		t.Fatalf("ReportAsKnown failed: %v", err)
+	}
+	if len(report) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(report))
+	}
+	if report[0].EmployeeID != "emp001" || report[0].VestedUnits != 4166 {
+		t.Errorf("Unexpected emp001 result: %+v", report[0])
+	}
+	if report[1].EmployeeID != "emp002" || report[1].VestedUnits != 2777 {
+		t.Errorf("Unexpected emp002 result: %+v", report[1])
+	}
+
+	report, err = service.ReportAsKnown(store, "", date(2023, 6, 1), date(2023, 6, 1))
+	if err != nil {
+		t.Fatalf("ReportAsKnown failed: %v", err)
+	}
+	if len(report) != 3 {
+		t.Errorf("Expected 3 results once emp003 is valid, got %d", len(report))
+	}
+}
+
+func TestReplayEmployeeAsKnown(t *testing.T) {
+	service := NewVestingService()
+	store := NewMemoryEventStore()
+
+	var now time.Time
+	store.now = func() time.Time { return now }
+
+	now = date(2021, 1, 1)
+	schedule := VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"}
+	for _, event := range []LifecycleEvent{
+		{Type: EventHired, EmployeeID: "emp001", EffectiveDate: date(2021, 1, 1)},
+		{Type: EventGrantIssued, EmployeeID: "emp001", Units: 10000, Schedule: schedule, EffectiveDate: date(2021, 1, 1)},
+	} {
+		if _, err := service.RecordEvent(store, event); err != nil {
+			t.Fatalf("RecordEvent failed: %v", err)
+		}
+	}
+
+	// The termination is only recorded months after it took effect
+	now = date(2023, 4, 1)
+	if _, err := service.RecordEvent(store, LifecycleEvent{Type: EventTerminated, EmployeeID: "emp001", EffectiveDate: date(2022, 7, 1)}); err != nil {
+		t.Fatalf("RecordEvent failed: %v", err)
+	}
+
+	asOfDate := date(2023, 1, 1)
+	before, err := service.ReplayEmployeeAsKnown(store, "", "emp001", asOfDate, date(2023, 3, 31))
+	if err != nil {
+		t.Fatalf("ReplayEmployeeAsKnown failed: %v", err)
+	}
+	after, err := service.ReplayEmployeeAsKnown(store, "", "emp001", asOfDate, date(2023, 4, 1))
+	if err != nil {
+		t.Fatalf("ReplayEmployeeAsKnown failed: %v", err)
+	}
+
+	// 24 months as known before the termination was recorded, 18 after
+	if before.Result.VestedUnits != 3333 {
+		t.Errorf("Expected 3333 vested units before the correction, got %d", before.Result.VestedUnits)
+	}
+	if after.Result.VestedUnits != 1666 {
+		t.Errorf("Expected 1666 vested units after the correction, got %d", after.Result.VestedUnits)
+	}
+
+	if _, err := service.ReplayEmployeeAsKnown(store, "", "emp001", asOfDate, date(2020, 1, 1)); err == nil {
+		t.Error("Expected error when no events were known")
+	}
+}
+
+func TestCalculateAsKnownIgnoresLaterSplits(t *testing.T) {
+	service := NewVestingService()
+	store := NewVersionedStore()
+	store.now = func() time.Time { return date(2023, 1, 5) }
+	store.PutEmployee(Employee{ID: "emp001", StartDate: date(2021, 1, 1), TotalUnits: 10000,
+		Schedule: VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"}}, date(2021, 1, 1))
+
+	// A 2:1 split effective in February is only recorded after the
+	// quarter-end report, however early the caller claims it was known
+	service.now = func() time.Time { return date(2023, 4, 15) }
+	if err := service.RecordSplit(StockSplit{EffectiveDate: date(2023, 2, 1), Numerator: 2, Denominator: 1,
+		RecordedAt: date(2023, 1, 1)}); err != nil {
+		t.Fatalf("RecordSplit failed: %v", err)
+	}
+
+	quarterEnd := date(2023, 3, 31)
+	tests := []struct {
+		name        string
+		knownAt     time.Time
+		vestedUnits int
+		totalUnits  int
+	}{
+		// 27 months from 2021-01-01, as reported at quarter end
+		{"as_reported", time.Date(2023, 3, 31, 23, 59, 0, 0, time.UTC), 4166, 10000},
+		{"split_known", date(2023, 5, 1), 8332, 20000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := service.CalculateAsKnown(store, "", "emp001", quarterEnd, tt.knownAt)
+			if err != nil {
+				t.Fatalf("CalculateAsKnown failed: %v", err)
+			}
+			if result.VestedUnits != tt.vestedUnits || result.VestedUnits+result.UnvestedUnits != tt.totalUnits {
+				t.Errorf("Expected %d of %d units vested, got %d of %d", tt.vestedUnits, tt.totalUnits,
+					result.VestedUnits, result.VestedUnits+result.UnvestedUnits)
+			}
+
+			report, err := service.ReportAsKnown(store, "", quarterEnd, tt.knownAt)
+			if err != nil {
+				t.Fatalf("ReportAsKnown failed: %v", err)
+			}
+			if len(report) != 1 || report[0] != result {
+				t.Errorf("Expected the report to match CalculateAsKnown, got %+v", report)
+			}
+		})
+	}
+}
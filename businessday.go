@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// HolidayCalendar holds the market holidays vest dates are moved off. A nil
+// calendar has no holidays, so only Saturdays and Sundays are skipped.
+type HolidayCalendar struct {
+	holidays map[civilDate]string
+}
+
+// civilDate is a calendar date without a time or location
+type civilDate struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+func civilDateOf(t time.Time) civilDate {
+	year, month, day := t.Date()
+	return civilDate{year, month, day}
+}
+
+// NewHolidayCalendar returns a calendar with the given holidays
+func NewHolidayCalendar(holidays ...time.Time) *HolidayCalendar {
+	calendar := &HolidayCalendar{holidays: make(map[civilDate]string)}
+	for _, holiday := range holidays {
+		calendar.holidays[civilDateOf(holiday)] = ""
+	}
+	return calendar
+}
+
+// LoadHolidayCalendar reads a holiday calendar from a file, see
+// ParseHolidayCalendar
+func LoadHolidayCalendar(path string) (*HolidayCalendar, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseHolidayCalendar(file)
+}
+
+// ParseHolidayCalendar reads one holiday per line as a 2006-01-02 date,
+// optionally followed by its name. Blank lines and lines starting with #
+// are skipped.
+func ParseHolidayCalendar(r io.Reader) (*HolidayCalendar, error) {
+	calendar := NewHolidayCalendar()
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		value, name, _ := strings.Cut(text, " ")
+		holiday, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid holiday on line %d: %w", line, err)
+		}
+		calendar.holidays[civilDateOf(holiday)] = strings.TrimSpace(name)
+	}
+	return calendar, scanner.Err()
+}
+
+// Holiday returns the name of the holiday on t's date, if it is one
+func (c *HolidayCalendar) Holiday(t time.Time) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	name, exists := c.holidays[civilDateOf(t)]
+	return name, exists
+}
+
+// IsBusinessDay reports whether t falls on a weekday that is not a holiday
+func (c *HolidayCalendar) IsBusinessDay(t time.Time) bool {
+	if weekday := t.Weekday(); weekday == time.Saturday || weekday == time.Sunday {
+		return false
+	}
+	_, holiday := c.Holiday(t)
+	return !holiday
+}
+
+// Adjust moves t to a business day under a business-day convention:
+// "following" moves it to the next business day, "preceding" to the previous
+// one, and "modified-following" to the next one unless that is in the
+// following month, in which case it moves to the previous one. Business days
+// and an empty convention leave t as it is.
+func (c *HolidayCalendar) Adjust(t time.Time, convention string) time.Time {
+	switch convention {
+	case "following":
+		return c.roll(t, 1)
+	case "preceding":
+		return c.roll(t, -1)
+	case "modified-following":
+		if following := c.roll(t, 1); following.Month() == t.Month() {
+			return following
+		}
+		return c.roll(t, -1)
+	}
+	return t
+}
+
+// roll steps t by days until it is a business day
+func (c *HolidayCalendar) roll(t time.Time, days int) time.Time {
+	for !c.IsBusinessDay(t) {
+		t = t.AddDate(0, 0, days)
+	}
+	return t
+}
+
+// validateBusinessDays ensures a schedule's business-day convention is known
+func validateBusinessDays(convention string) error {
+	switch convention {
+	case "", "following", "modified-following", "preceding":
+		return nil
+	}
+	return fmt.Errorf("invalid business-day convention: %s", convention)
+}
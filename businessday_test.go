@@ -0,0 +1,187 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHolidayCalendarAdjust(t *testing.T) {
+	// Memorial Day and Labor Day 2024
+	calendar := NewHolidayCalendar(date(2024, 5, 27), date(2024, 9, 2))
+
+	tests := []struct {
+		name       string
+		calendar   *HolidayCalendar
+		date       time.Time
+		convention string
+		expected   time.Time
+	}{
+		{"business_day", calendar, date(2024, 6, 12), "following", date(2024, 6, 12)},
+		{"following_weekend", calendar, date(2024, 6, 15), "following", date(2024, 6, 17)},
+		{"preceding_weekend", calendar, date(2024, 6, 15), "preceding", date(2024, 6, 14)},
+		{"following_into_holiday", calendar, date(2024, 5, 25), "following", date(2024, 5, 28)},
+		{"preceding_holiday", calendar, date(2024, 5, 27), "preceding", date(2024, 5, 24)},
+		{"modified_following_same_month", calendar, date(2024, 6, 15), "modified-following", date(2024, 6, 17)},
+		{"modified_following_month_end", calendar, date(2024, 8, 31), "modified-following", date(2024, 8, 30)},
+		{"following_month_end", calendar, date(2024, 8, 31), "following", date(2024, 9, 3)},
+		{"no_convention", calendar, date(2024, 6, 15), "", date(2024, 6, 15)},
+		{"weekends_only", nil, date(2024, 5, 27), "following", date(2024, 5, 27)},
+		{"weekends_only_weekend", nil, date(2024, 6, 16), "preceding", date(2024, 6, 14)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.calendar.Adjust(tt.date, tt.convention); !got.Equal(tt.expected) {
+				t.Errorf("Expected %s, got %s", tt.expected.Format("2006-01-02"), got.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestLoadHolidayCalendar(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "holidays.txt")
+	data := "# NYSE holidays\n2024-12-25 Christmas Day\n\n2025-01-01\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	calendar, err := LoadHolidayCalendar(path)
+	if err != nil {
+		t.Fatalf("LoadHolidayCalendar failed: %v", err)
+	}
+	if name, holiday := calendar.Holiday(date(2024, 12, 25)); !holiday || name != "Christmas Day" {
+		t.Errorf("Expected Christmas Day on 2024-12-25, got %q", name)
+	}
+	if _, holiday := calendar.Holiday(date(2025, 1, 1)); !holiday {
+		t.Error("Expected an unnamed holiday on 2025-01-01")
+	}
+	if calendar.IsBusinessDay(date(2024, 12, 25)) || !calendar.IsBusinessDay(date(2024, 12, 24)) {
+		t.Error("Expected only the holiday to be skipped")
+	}
+
+	if _, err := ParseHolidayCalendar(strings.NewReader("2024-12-25\nDecember 26\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected an invalid date on line 2 to be rejected, got %v", err)
+	}
+	if _, err := LoadHolidayCalendar(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("Expected a missing file to be rejected")
+	}
+}
+
+func TestBusinessDayVesting(t *testing.T) {
+	service := NewVestingService()
+	employee := Employee{
+		ID:         "emp001",
+		StartDate:  date(2024, 1, 15),
+		TotalUnits: 1200,
+		Schedule:   VestingSchedule{CliffMonths: 0, VestingMonths: 12, VestingType: "linear", BusinessDays: "following"},
+		Calendar:   NewHolidayCalendar(date(2024, 10, 15)),
+	}
+
+	// The 15th falls on a weekend in June, September and December and is a
+	// holiday in October
+	expected := []time.Time{
+		date(2024, 1, 15), date(2024, 2, 15), date(2024, 3, 15), date(2024, 4, 15),
+		date(2024, 5, 15), date(2024, 6, 17), date(2024, 7, 15), date(2024, 8, 15),
+		date(2024, 9, 16), date(2024, 10, 16), date(2024, 11, 15), date(2024, 12, 16),
+	}
+	events := vestEvents(employee)
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d", len(expected), len(events))
+	}
+	for i, event := range events {
+		if !event.Date.Equal(expected[i]) {
+			t.Errorf("Event %d: expected %s, got %s", i+1, expected[i].Format("2006-01-02"), event.Date.Format("2006-01-02"))
+		}
+	}
+
+	// Units vest on the moved date
+	tests := []struct {
+		asOfDate     time.Time
+		vestedUnits  int
+		nextVestDate time.Time
+	}{
+		{date(2024, 6, 15), 500, date(2024, 6, 17)},
+		{date(2024, 6, 17), 500, date(2024, 6, 17)},
+		{date(2024, 6, 18), 600, date(2024, 7, 15)},
+		{date(2024, 10, 1), 900, date(2024, 10, 16)},
+	}
+	for _, tt := range tests {
+		result, err := service.calculateVesting(employee, tt.asOfDate)
+		if err != nil {
+			t.Fatalf("calculateVesting failed: %v", err)
+		}
+		if result.VestedUnits != tt.vestedUnits || !result.NextVestDate.Equal(tt.nextVestDate) {
+			t.Errorf("As of %s: expected %d units vesting next on %s, got %d on %s", tt.asOfDate.Format("2006-01-02"),
+				tt.vestedUnits, tt.nextVestDate.Format("2006-01-02"), result.VestedUnits, result.NextVestDate.Format("2006-01-02"))
+		}
+	}
+
+	// Employees without a calendar use the tenant's
+	if err := service.RegisterTenant("acme", TenantSettings{Calendar: employee.Calendar}); err != nil {
+		t.Fatalf("RegisterTenant failed: %v", err)
+	}
+	tenantEmployee := employee
+	tenantEmployee.TenantID = "acme"
+	tenantEmployee.Calendar = nil
+	if result, _ := service.calculateVesting(tenantEmployee, date(2024, 10, 1)); !result.NextVestDate.Equal(date(2024, 10, 16)) {
+		t.Errorf("Expected the tenant's holiday to move the next vest to 2024-10-16, got %s", result.NextVestDate.Format("2006-01-02"))
+	}
+	withoutCalendar := employee
+	withoutCalendar.Calendar = nil
+	if result, _ := service.calculateVesting(withoutCalendar, date(2024, 10, 1)); !result.NextVestDate.Equal(date(2024, 10, 15)) {
+		t.Errorf("Expected weekends alone to keep the next vest on 2024-10-15, got %s", result.NextVestDate.Format("2006-01-02"))
+	}
+
+	if err := ValidateSchedule(VestingSchedule{CliffMonths: 0, VestingMonths: 12, VestingType: "linear", BusinessDays: "nearest"}); err == nil {
+		t.Error("Expected an unknown business-day convention to be rejected")
+	}
+}
+
+func TestBusinessDayConsistency(t *testing.T) {
+	service := NewVestingService()
+	calendar := NewHolidayCalendar(date(2024, 5, 27), date(2024, 9, 2), date(2025, 3, 31))
+
+	for _, convention := range []string{"following", "preceding", "modified-following"} {
+		for _, vestDay := range []string{"", "anniversary"} {
+			employee := Employee{
+				ID:         "emp001",
+				StartDate:  date(2024, 3, 31),
+				TotalUnits: 2400,
+				Schedule: VestingSchedule{CliffMonths: 6, VestingMonths: 24, VestingType: "linear",
+					VestDay: vestDay, BusinessDays: convention},
+				Calendar: calendar,
+			}
+
+			events := vestEvents(employee)
+			points, err := service.Timeline(employee, employee.StartDate, employee.StartDate.AddDate(2, 1, 0), DailyStep)
+			if err != nil {
+				t.Fatalf("Timeline failed: %v", err)
+			}
+			for _, point := range points {
+				expected, _ := service.calculateVesting(employee, point.Date)
+				if point.VestedUnits != expected.VestedUnits {
+					t.Fatalf("%s %s: timeline at %s has %d vested units, calculateVesting %d", convention, vestDay,
+						point.Date.Format("2006-01-02"), point.VestedUnits, expected.VestedUnits)
+				}
+
+				sum := 0
+				for _, event := range eventsBetween(events, employee.StartDate.AddDate(0, 0, -7), point.Date) {
+					sum += event.Units
+				}
+				if sum != expected.VestedUnits {
+					t.Fatalf("%s %s: events before %s sum to %d, calculateVesting %d", convention, vestDay,
+						point.Date.Format("2006-01-02"), sum, expected.VestedUnits)
+				}
+			}
+
+			for _, event := range events {
+				if !calendar.IsBusinessDay(event.Date) {
+					t.Errorf("%s %s: vest on %s is not a business day", convention, vestDay, event.Date.Format("2006-01-02"))
+				}
+			}
+		}
+	}
+}
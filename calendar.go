@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// CalendarEvent is one employee's vest on a calendar day
+type CalendarEvent struct {
+	EmployeeID      string
+	Units           int
+	CumulativeUnits int
+	Cliff           bool
+	Final           bool
+	Exchange        bool
+	Split           bool
+}
+
+// CalendarDay groups the vest events that fall on the same date
+type CalendarDay struct {
+	Date       time.Time
+	Events     []CalendarEvent
+	TotalUnits int
+}
+
+// UpcomingVests returns every vest event of the default tenant's employees
+// last passed to ProcessBatch that falls in the days after asOfDate, starting
+// with asOfDate itself. Days are in chronological order and events within a
+// day are ordered by employee ID.
+func (vs *VestingService) UpcomingVests(asOfDate time.Time, days int) ([]CalendarDay, error) {
+	return vs.upcomingVests(defaultTenant, asOfDate, days)
+}
+
+func (vs *VestingService) upcomingVests(tenantID string, asOfDate time.Time, days int) ([]CalendarDay, error) {
+	if days <= 0 {
+		return nil, fmt.Errorf("invalid number of days: %d", days)
+	}
+
+	vs.mu.Lock()
+	employees := make([]Employee, 0, len(vs.cache.employees))
+	for key, employee := range vs.cache.employees {
+		if key.TenantID == tenantID {
+			employees = append(employees, employee)
+		}
+	}
+	vs.mu.Unlock()
+
+	sort.Slice(employees, func(i, j int) bool {
+		return employees[i].ID < employees[j].ID
+	})
+
+	end := asOfDate.AddDate(0, 0, days)
+	byDate := make(map[time.Time]*CalendarDay)
+	var calendar []*CalendarDay
+
+	for _, employee := range employees {
+		employee = vs.applyTenantSettings(employee)
+		for _, event := range eventsBetween(vs.splitEvents(employee, vestEvents(employee)), asOfDate, end) {
+			date := time.Date(event.Date.Year(), event.Date.Month(), event.Date.Day(), 0, 0, 0, 0, event.Date.Location())
+			day, exists := byDate[date]
+			if !exists {
+				day = &CalendarDay{Date: date}
+				byDate[date] = day
+				calendar = append(calendar, day)
+			}
+
+			day.Events = append(day.Events, CalendarEvent{
+				EmployeeID:      employee.ID,
+				Units:           event.Units,
+				CumulativeUnits: event.CumulativeUnits,
+				Cliff:           event.Cliff,
+				Final:           event.Final,
+				Exchange:        event.Exchange,
+				Split:           event.Split,
+			})
+			day.TotalUnits += event.Units
+		}
+	}
+
+	sort.Slice(calendar, func(i, j int) bool {
+		return calendar[i].Date.Before(calendar[j].Date)
+	})
+
+	result := make([]CalendarDay, len(calendar))
+	for i, day := range calendar {
+		result[i] = *day
+	}
+	return result, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUpcomingVests(t *testing.T) {
+	service := NewVestingService()
+
+	employees := []Employee{
+		{
+			ID:         "cal_b",
+			StartDate:  time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+			TotalUnits: 36000,
+			Schedule:   VestingSchedule{CliffMonths: 12, VestingMonths: 36, VestingType: "linear"},
+		},
+		{
+			ID:         "cal_a",
+			StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			TotalUnits: 24000,
+			Schedule:   VestingSchedule{CliffMonths: 12, VestingMonths: 36, VestingType: "linear"},
+		},
+		{
+			ID:         "cal_c",
+			StartDate:  time.Date(2022, 12, 20, 0, 0, 0, 0, time.UTC),
+			TotalUnits: 1000,
+			Schedule:   VestingSchedule{CliffMonths: 12, VestingMonths: 36, VestingType: "linear"},
+		},
+	}
+
+	asOfDate := time.Date(2022, 12, 15, 0, 0, 0, 0, time.UTC)
+	if err := service.ProcessBatch(employees, asOfDate); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+
+	calendar, err := service.UpcomingVests(asOfDate, 60)
+	if err != nil {
+		t.Fatalf("UpcomingVests failed: %v", err)
+	}
+
+	// cal_b reaches its cliff on 2023-01-01, cal_a is already vesting
+	// monthly and cal_c does not reach its cliff within the window
+	expected := []struct {
+		date   time.Time
+		events []CalendarEvent
+	}{
+		{
+			date: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			events: []CalendarEvent{
+				{EmployeeID: "cal_a", Units: 1000, CumulativeUnits: 13000},
+				{EmployeeID: "cal_b", Units: 1500, CumulativeUnits: 1500, Cliff: true},
+			},
+		},
+		{
+			date: time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC),
+			events: []CalendarEvent{
+				{EmployeeID: "cal_a", Units: 1000, CumulativeUnits: 14000},
+				{EmployeeID: "cal_b", Units: 1500, CumulativeUnits: 3000},
+			},
+		},
+	}
+
+	if len(calendar) != len(expected) {
+		t.Fatalf("Expected %d days, got %d: %+v", len(expected), len(calendar), calendar)
+	}
+
+	for i, day := range calendar {
+		if !day.Date.Equal(expected[i].date) {
+			t.Errorf("Day %d: expected %s, got %s", i, expected[i].date.Format("2006-01-02"), day.Date.Format("2006-01-02"))
+		}
+		if len(day.Events) != len(expected[i].events) {
+			t.Errorf("Day %d: expected %d events, got %d", i, len(expected[i].events), len(day.Events))
+			continue
+		}
+		total := 0
+		for j, event := range day.Events {
+			want := expected[i].events[j]
+			if event.EmployeeID != want.EmployeeID || event.Units != want.Units ||
+				event.CumulativeUnits != want.CumulativeUnits || event.Cliff != want.Cliff {
+				t.Errorf("Day %d event %d: expected %+v, got %+v", i, j, want, event)
+			}
+			total += event.Units
+		}
+		if day.TotalUnits != total {
+			t.Errorf("Day %d: expected total %d, got %d", i, total, day.TotalUnits)
+		}
+	}
+
+	if _, err := service.UpcomingVests(asOfDate, 0); err == nil {
+		t.Error("Expected error for zero days")
+	}
+
+	service.ClearCache()
+	calendar, err = service.UpcomingVests(asOfDate, 60)
+	if err != nil || len(calendar) != 0 {
+		t.Errorf("Expected an empty calendar after clearing the cache, got %d days (%v)", len(calendar), err)
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Grant term changes recorded in a GrantTerms history
+const (
+	TermsGranted   = "grant"
+	TermsAmended   = "amendment"
+	TermsRepriced  = "repricing"
+	TermsExchanged = "exchange"
+)
+
+// GrantTerms are the terms of an employee's grant from EffectiveDate on
+type GrantTerms struct {
+	EffectiveDate time.Time
+	Change        string
+	TotalUnits    int
+	StrikePrice   float64
+	Schedule      VestingSchedule
+}
+
+// Reprice sets a new strike price for the employee's grant from
+// effectiveDate on. The original strike price is kept.
+func (e *Employee) Reprice(effectiveDate time.Time, strikePrice float64) error {
+	repricings := append(append([]Repricing(nil), e.Repricings...), Repricing{
+		EffectiveDate: effectiveDate,
+		StrikePrice:   strikePrice,
+	})
+	if err := ValidateRepricings(repricings); err != nil {
+		return err
+	}
+
+	sort.SliceStable(repricings, func(i, j int) bool {
+		return repricings[i].EffectiveDate.Before(repricings[j].EffectiveDate)
+	})
+	e.Repricings = repricings
+	return nil
+}
+
+// ExchangeGrant exchanges the employee's grant for a replacement grant. The
+// original grant's terms are kept, and a grant can only be exchanged once.
+func (e *Employee) ExchangeGrant(exchange GrantExchange) error {
+	if e.Exchange != nil {
+		return fmt.Errorf("grant was already exchanged on %s", e.Exchange.EffectiveDate.Format("2006-01-02"))
+	}
+	if err := ValidateExchange(&exchange); err != nil {
+		return err
+	}
+	if !exchange.EffectiveDate.After(commencementDate(*e)) {
+		return fmt.Errorf("exchange effective %s is not after the vesting commencement date", exchange.EffectiveDate.Format("2006-01-02"))
+	}
+	e.Exchange = &exchange
+	return nil
+}
+
+// ValidateRepricings ensures every repricing has an effective date and a
+// strike price that is not negative, and that no two take effect on the same
+// date
+func ValidateRepricings(repricings []Repricing) error {
+	seen := make(map[time.Time]bool)
+	for _, repricing := range repricings {
+		if repricing.EffectiveDate.IsZero() {
+			return fmt.Errorf("repricing has no effective date")
+		}
+		if repricing.StrikePrice < 0 {
+			return fmt.Errorf("invalid strike price: %.4f", repricing.StrikePrice)
+		}
+		date := repricing.EffectiveDate.UTC()
+		if seen[date] {
+			return fmt.Errorf("more than one repricing effective %s", repricing.EffectiveDate.Format("2006-01-02"))
+		}
+		seen[date] = true
+	}
+	return nil
+}
+
+// ValidateExchange ensures an exchange has an effective date, a positive
+// conversion ratio, a strike price that is not negative and a valid reset
+// schedule. A nil exchange is valid.
+func ValidateExchange(exchange *GrantExchange) error {
+	if exchange == nil {
+		return nil
+	}
+	if exchange.EffectiveDate.IsZero() {
+		return fmt.Errorf("exchange has no effective date")
+	}
+	if exchange.Numerator <= 0 || exchange.Denominator <= 0 {
+		return fmt.Errorf("invalid conversion ratio: %d:%d", exchange.Numerator, exchange.Denominator)
+	}
+	if exchange.StrikePrice < 0 {
+		return fmt.Errorf("invalid strike price: %.4f", exchange.StrikePrice)
+	}
+	if exchange.ResetSchedule != nil {
+		if err := ValidateSchedule(*exchange.ResetSchedule); err != nil {
+			return fmt.Errorf("reset schedule: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateTermChanges validates the amendments, repricings and exchange of
+// the employee's grant
+func validateTermChanges(employee Employee) error {
+	if err := ValidateAmendments(employee.Amendments); err != nil {
+		return err
+	}
+	if err := ValidateRepricings(employee.Repricings); err != nil {
+		return err
+	}
+	return ValidateExchange(employee.Exchange)
+}
+
+// grantAt returns the grant in force as of date and the ratio its vested
+// units convert at. Before the exchange, or when the employee was
+// terminated by it, that is the original grant at 1:1.
+func grantAt(employee Employee, date time.Time) (Employee, SplitBasis) {
+	if !exchangedBy(employee, date) {
+		employee.Exchange = nil
+		return employee, grantBasis
+	}
+	return replacementGrant(employee)
+}
+
+// exchangedBy reports whether the employee's grant was exchanged before
+// date. An exchange on or after the termination date has no effect.
+func exchangedBy(employee Employee, date time.Time) bool {
+	exchange := employee.Exchange
+	return exchange != nil && date.After(exchange.EffectiveDate) && !terminatedBy(employee, exchange.EffectiveDate)
+}
+
+// replacementGrant returns the grant issued by the employee's exchange.
+// Amendments and repricings after the exchange apply to the replacement.
+func replacementGrant(employee Employee) (Employee, SplitBasis) {
+	exchange := employee.Exchange
+	ratio := SplitBasis{Numerator: exchange.Numerator, Denominator: exchange.Denominator}
+
+	replacement := employee
+	replacement.Exchange = nil
+	replacement.StrikePrice = exchange.StrikePrice
+	replacement.Repricings = nil
+	for _, repricing := range employee.Repricings {
+		if repricing.EffectiveDate.After(exchange.EffectiveDate) {
+			replacement.Repricings = append(replacement.Repricings, repricing)
+		}
+	}
+
+	if exchange.ResetSchedule == nil {
+		return replacement, ratio
+	}
+
+	// A reset grant is a new grant of the converted units
+	replacement.VestingCommencementDate = exchange.EffectiveDate
+	replacement.GrantDate = exchange.EffectiveDate
+	replacement.TotalUnits = ratio.Units(employee.TotalUnits)
+	replacement.Schedule = *exchange.ResetSchedule
+	if replacement.Schedule.Rounding == "" {
+		replacement.Schedule.Rounding = employee.Schedule.Rounding
+		replacement.Schedule.Remainder = employee.Schedule.Remainder
+	}
+	if replacement.Schedule.VestDay == "" {
+		replacement.Schedule.VestDay = employee.Schedule.VestDay
+	}
+	if replacement.Schedule.BusinessDays == "" {
+		replacement.Schedule.BusinessDays = employee.Schedule.BusinessDays
+	}
+	replacement.Amendments = nil
+	for _, amendment := range employee.Amendments {
+		if amendment.EffectiveDate.After(exchange.EffectiveDate) {
+			replacement.Amendments = append(replacement.Amendments, amendment)
+		}
+	}
+	return replacement, grantBasis
+}
+
+// grantUnitsAfter returns the total units of the grant once the events dated
+// date have vested
+func grantUnitsAfter(employee Employee, date time.Time) int {
+	grant, conversion := grantAt(employee, date.AddDate(0, 0, 1))
+	return conversion.Units(grant.TotalUnits)
+}
+
+// strikeAt returns the grant's strike price as of date, after any repricing
+func strikeAt(grant Employee, date time.Time) float64 {
+	strikePrice := grant.StrikePrice
+	for _, repricing := range grant.Repricings {
+		if !repricing.EffectiveDate.After(date) {
+			strikePrice = repricing.StrikePrice
+		}
+	}
+	return strikePrice
+}
+
+// exchangeEvents replaces the events of the old grant after the exchange
+// with an event converting the units vested by then and the events of the
+// replacement grant
+func exchangeEvents(employee Employee, old []VestEvent) []VestEvent {
+	exchange := employee.Exchange
+	replacement, conversion := replacementGrant(employee)
+
+	var events, after []VestEvent
+	before, atExchange := 0, 0
+	for _, event := range old {
+		switch {
+		case event.Date.Before(exchange.EffectiveDate):
+			events = append(events, event)
+			before = event.CumulativeUnits
+			atExchange = before
+		case !event.Date.After(exchange.EffectiveDate):
+			atExchange = event.CumulativeUnits
+		default:
+			after = append(after, event)
+		}
+	}
+
+	var following []VestEvent
+	converted := 0
+	if exchange.ResetSchedule != nil {
+		following = vestEvents(replacement)
+	} else {
+		// The old grant's events carry on with converted units
+		converted = conversion.Units(atExchange)
+		totalUnits := conversion.Units(employee.TotalUnits)
+		previous, final := converted, converted == totalUnits
+		for _, event := range after {
+			cumulative := conversion.Units(event.CumulativeUnits)
+			if cumulative == previous {
+				continue
+			}
+			event.Units = cumulative - previous
+			event.CumulativeUnits = cumulative
+			event.Final = !final && cumulative == totalUnits
+			final = final || event.Final
+			following = append(following, event)
+			previous = cumulative
+		}
+	}
+
+	if converted != before {
+		events = append(events, VestEvent{
+			EmployeeID:      employee.ID,
+			Date:            exchange.EffectiveDate,
+			Units:           converted - before,
+			CumulativeUnits: converted,
+			Exchange:        true,
+		})
+	}
+	return append(events, following...)
+}
+
+// TermsHistory returns the terms of the employee's grant after each change,
+// oldest first. The original terms are always the first entry.
+func TermsHistory(employee Employee) []GrantTerms {
+	current := GrantTerms{
+		EffectiveDate: grantDate(employee),
+		Change:        TermsGranted,
+		TotalUnits:    employee.TotalUnits,
+		StrikePrice:   employee.StrikePrice,
+		Schedule:      employee.Schedule,
+	}
+	history := []GrantTerms{current}
+
+	var changes []GrantTerms
+	for _, amendment := range employee.Amendments {
+		changes = append(changes, GrantTerms{EffectiveDate: amendment.EffectiveDate, Change: TermsAmended, Schedule: amendment.Schedule})
+	}
+	for _, repricing := range employee.Repricings {
+		changes = append(changes, GrantTerms{EffectiveDate: repricing.EffectiveDate, Change: TermsRepriced, StrikePrice: repricing.StrikePrice})
+	}
+	if exchange := employee.Exchange; exchange != nil {
+		ratio := SplitBasis{Numerator: exchange.Numerator, Denominator: exchange.Denominator}
+		terms := GrantTerms{
+			EffectiveDate: exchange.EffectiveDate,
+			Change:        TermsExchanged,
+			TotalUnits:    ratio.Units(employee.TotalUnits),
+			StrikePrice:   exchange.StrikePrice,
+		}
+		if exchange.ResetSchedule != nil {
+			terms.Schedule = *exchange.ResetSchedule
+		}
+		changes = append(changes, terms)
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].EffectiveDate.Before(changes[j].EffectiveDate)
+	})
+
+	for _, change := range changes {
+		current.EffectiveDate = change.EffectiveDate
+		current.Change = change.Change
+		switch change.Change {
+		case TermsAmended:
+			current.Schedule = change.Schedule
+		case TermsRepriced:
+			current.StrikePrice = change.StrikePrice
+		case TermsExchanged:
+			current.TotalUnits = change.TotalUnits
+			current.StrikePrice = change.StrikePrice
+			if change.Schedule != (VestingSchedule{}) {
+				current.Schedule = change.Schedule
+			}
+		}
+		history = append(history, current)
+	}
+	return history
+}
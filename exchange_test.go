@@ -0,0 +1,313 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRepricingAndExchange(t *testing.T) {
+	service := NewVestingService()
+	reset := VestingSchedule{CliffMonths: 0, VestingMonths: 24, VestingType: "linear"}
+	resetWithCliff := VestingSchedule{CliffMonths: 12, VestingMonths: 36, VestingType: "linear"}
+
+	tests := []struct {
+		name          string
+		repricings    []Repricing
+		exchange      *GrantExchange
+		termination   time.Time
+		asOfDate      time.Time
+		vestedUnits   int
+		unvestedUnits int
+		strikePrice   float64
+	}{
+		{
+			name:          "before_repricing",
+			repricings:    []Repricing{{date(2022, 6, 1), 2.5}},
+			asOfDate:      date(2022, 5, 31),
+			vestedUnits:   1388, // 17 months
+			unvestedUnits: 8612,
+			strikePrice:   4,
+		},
+		{
+			name:          "repriced",
+			repricings:    []Repricing{{date(2022, 6, 1), 2.5}},
+			asOfDate:      date(2022, 6, 1),
+			vestedUnits:   1388,
+			unvestedUnits: 8612,
+			strikePrice:   2.5,
+		},
+		{
+			name:          "on_exchange_date",
+			exchange:      &GrantExchange{EffectiveDate: date(2023, 1, 1), Numerator: 1, Denominator: 2, StrikePrice: 1},
+			asOfDate:      date(2023, 1, 1),
+			vestedUnits:   3333, // 24 months of the old grant
+			unvestedUnits: 6667,
+			strikePrice:   4,
+		},
+		{
+			name:          "exchanged_keeping_vesting",
+			exchange:      &GrantExchange{EffectiveDate: date(2023, 1, 1), Numerator: 1, Denominator: 2, StrikePrice: 1},
+			asOfDate:      date(2023, 1, 2),
+			vestedUnits:   1805, // 3611 after 25 months, converted 1:2
+			unvestedUnits: 3195,
+			strikePrice:   1,
+		},
+		{
+			name:          "exchanged_fully_vested",
+			exchange:      &GrantExchange{EffectiveDate: date(2023, 1, 1), Numerator: 1, Denominator: 2, StrikePrice: 1},
+			asOfDate:      date(2025, 6, 1),
+			vestedUnits:   5000,
+			unvestedUnits: 0,
+			strikePrice:   1,
+		},
+		{
+			name:          "exchanged_with_reset",
+			exchange:      &GrantExchange{EffectiveDate: date(2023, 1, 1), Numerator: 1, Denominator: 2, StrikePrice: 1, ResetSchedule: &reset},
+			asOfDate:      date(2023, 7, 1),
+			vestedUnits:   1250, // 6 of 24 months of 5000 units
+			unvestedUnits: 3750,
+			strikePrice:   1,
+		},
+		{
+			name:          "reset_with_cliff",
+			exchange:      &GrantExchange{EffectiveDate: date(2023, 1, 1), Numerator: 1, Denominator: 2, StrikePrice: 1, ResetSchedule: &resetWithCliff},
+			asOfDate:      date(2023, 7, 1),
+			vestedUnits:   0,
+			unvestedUnits: 5000,
+			strikePrice:   1,
+		},
+		{
+			name:          "repriced_after_exchange",
+			repricings:    []Repricing{{date(2022, 6, 1), 2.5}, {date(2024, 1, 1), 0.5}},
+			exchange:      &GrantExchange{EffectiveDate: date(2023, 1, 1), Numerator: 1, Denominator: 2, StrikePrice: 1, ResetSchedule: &reset},
+			asOfDate:      date(2024, 1, 1),
+			vestedUnits:   2500,
+			unvestedUnits: 2500,
+			strikePrice:   0.5,
+		},
+		{
+			name:          "exchange_after_termination_ignored",
+			exchange:      &GrantExchange{EffectiveDate: date(2023, 1, 1), Numerator: 1, Denominator: 2, StrikePrice: 1, ResetSchedule: &reset},
+			termination:   date(2022, 6, 1),
+			asOfDate:      date(2024, 1, 1),
+			vestedUnits:   1388, // 17 months under the original grant
+			unvestedUnits: 8612,
+			strikePrice:   4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			employee := Employee{
+				ID:              "emp001",
+				StartDate:       date(2021, 1, 1),
+				TotalUnits:      10000,
+				Schedule:        VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"},
+				StrikePrice:     4,
+				Repricings:      tt.repricings,
+				Exchange:        tt.exchange,
+				TerminationDate: tt.termination,
+			}
+
+			result, err := service.calculateVesting(employee, tt.asOfDate)
+			if err != nil {
+				t.Fatalf("calculateVesting failed: %v", err)
+			}
+			if result.VestedUnits != tt.vestedUnits || result.UnvestedUnits != tt.unvestedUnits {
+				t.Errorf("Expected %d vested and %d unvested, got %d and %d",
+					tt.vestedUnits, tt.unvestedUnits, result.VestedUnits, result.UnvestedUnits)
+			}
+			if math.Abs(result.StrikePrice-tt.strikePrice) > 1e-9 {
+				t.Errorf("Expected strike price %.4f, got %.4f", tt.strikePrice, result.StrikePrice)
+			}
+
+			// Events and timeline points agree with calculateVesting
+			events := vestEvents(employee)
+			from := employee.StartDate
+			points, err := service.Timeline(employee, from, date(2026, 1, 1), TimelineStep{Days: 10})
+			if err != nil {
+				t.Fatalf("Timeline failed: %v", err)
+			}
+			for _, point := range points {
+				expected, _ := service.calculateVesting(employee, point.Date)
+				if point.VestedUnits != expected.VestedUnits || point.UnvestedUnits != expected.UnvestedUnits {
+					t.Fatalf("Timeline at %s has %d/%d units, calculateVesting %d/%d", point.Date.Format("2006-01-02"),
+						point.VestedUnits, point.UnvestedUnits, expected.VestedUnits, expected.UnvestedUnits)
+				}
+
+				sum := 0
+				for _, event := range eventsBetween(events, from, point.Date) {
+					sum += event.Units
+				}
+				if sum != expected.VestedUnits {
+					t.Fatalf("Events before %s sum to %d, calculateVesting %d",
+						point.Date.Format("2006-01-02"), sum, expected.VestedUnits)
+				}
+			}
+		})
+	}
+}
+
+func TestExchangeEvents(t *testing.T) {
+	reset := VestingSchedule{CliffMonths: 0, VestingMonths: 24, VestingType: "linear"}
+	employee := Employee{
+		ID:         "emp001",
+		StartDate:  date(2021, 1, 1),
+		TotalUnits: 10000,
+		Schedule:   VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"},
+		Exchange:   &GrantExchange{EffectiveDate: date(2023, 1, 1), Numerator: 1, Denominator: 2, ResetSchedule: &reset},
+	}
+
+	var exchange []VestEvent
+	finals := 0
+	for _, event := range vestEvents(employee) {
+		if event.Exchange {
+			exchange = append(exchange, event)
+		}
+		if event.Final {
+			finals++
+		}
+	}
+	// 3333 units vested through the month starting 2022-12-01 are cancelled
+	if len(exchange) != 1 || exchange[0].Units != -3333 || exchange[0].CumulativeUnits != 0 ||
+		!exchange[0].Date.Equal(date(2023, 1, 1)) {
+		t.Errorf("Expected one exchange event cancelling 3333 units, got %+v", exchange)
+	}
+	if finals != 1 {
+		t.Errorf("Expected one final event, got %d", finals)
+	}
+	if total := grantUnitsAfter(employee, date(2023, 1, 1)); total != 5000 {
+		t.Errorf("Expected 5000 units after the exchange, got %d", total)
+	}
+}
+
+func TestExplainExchange(t *testing.T) {
+	employee := Employee{
+		ID:         "emp001",
+		StartDate:  date(2021, 1, 1),
+		TotalUnits: 10000,
+		Schedule:   VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"},
+		Exchange:   &GrantExchange{EffectiveDate: date(2023, 1, 1), Numerator: 1, Denominator: 2},
+	}
+
+	_, explanation, err := NewVestingService().ExplainVesting(employee, date(2023, 1, 2))
+	if err != nil {
+		t.Fatalf("ExplainVesting failed: %v", err)
+	}
+	if !explanation.ExchangedOn.Equal(date(2023, 1, 1)) || explanation.ConversionRatio != "1:2" {
+		t.Errorf("Expected exchange on 2023-01-01 at 1:2, got %s at %q",
+			explanation.ExchangedOn.Format("2006-01-02"), explanation.ConversionRatio)
+	}
+	if explanation.VestedUnits != 1805 {
+		t.Errorf("Expected 1805 vested units, got %d", explanation.VestedUnits)
+	}
+}
+
+func TestTermsHistory(t *testing.T) {
+	reset := VestingSchedule{CliffMonths: 0, VestingMonths: 24, VestingType: "linear"}
+	employee := Employee{
+		ID:          "emp001",
+		StartDate:   date(2021, 1, 1),
+		TotalUnits:  10000,
+		Schedule:    VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"},
+		StrikePrice: 4,
+	}
+	original := employee
+
+	if err := employee.ExchangeGrant(GrantExchange{EffectiveDate: date(2023, 1, 1), Numerator: 1, Denominator: 2, StrikePrice: 1, ResetSchedule: &reset}); err != nil {
+		t.Fatalf("ExchangeGrant failed: %v", err)
+	}
+	if err := employee.Reprice(date(2022, 6, 1), 2.5); err != nil {
+		t.Fatalf("Reprice failed: %v", err)
+	}
+	if err := employee.AmendSchedule(date(2022, 1, 1), VestingSchedule{CliffMonths: 0, VestingMonths: 36, VestingType: "linear"}); err != nil {
+		t.Fatalf("AmendSchedule failed: %v", err)
+	}
+	if employee.StrikePrice != original.StrikePrice || employee.TotalUnits != original.TotalUnits || employee.Schedule != original.Schedule {
+		t.Error("Original grant terms were overwritten")
+	}
+
+	history := TermsHistory(employee)
+	expected := []GrantTerms{
+		{date(2021, 1, 1), TermsGranted, 10000, 4, original.Schedule},
+		{date(2022, 1, 1), TermsAmended, 10000, 4, VestingSchedule{CliffMonths: 0, VestingMonths: 36, VestingType: "linear"}},
+		{date(2022, 6, 1), TermsRepriced, 10000, 2.5, VestingSchedule{CliffMonths: 0, VestingMonths: 36, VestingType: "linear"}},
+		{date(2023, 1, 1), TermsExchanged, 5000, 1, reset},
+	}
+	if len(history) != len(expected) {
+		t.Fatalf("Expected %d history entries, got %+v", len(expected), history)
+	}
+	for i := range expected {
+		if history[i] != expected[i] {
+			t.Errorf("Entry %d: expected %+v, got %+v", i, expected[i], history[i])
+		}
+	}
+
+	invalid := []struct {
+		name string
+		err  error
+	}{
+		{"second_exchange", employee.ExchangeGrant(GrantExchange{EffectiveDate: date(2024, 1, 1), Numerator: 1, Denominator: 1})},
+		{"negative_strike", employee.Reprice(date(2024, 1, 1), -1)},
+		{"repricing_same_date", employee.Reprice(date(2022, 6, 1), 3)},
+		{"repricing_no_date", employee.Reprice(time.Time{}, 3)},
+		{"exchange_zero_ratio", original.ExchangeGrant(GrantExchange{EffectiveDate: date(2023, 1, 1), Numerator: 0, Denominator: 2})},
+		{"exchange_before_start", original.ExchangeGrant(GrantExchange{EffectiveDate: date(2020, 1, 1), Numerator: 1, Denominator: 2})},
+		{"exchange_invalid_reset", original.ExchangeGrant(GrantExchange{EffectiveDate: date(2023, 1, 1), Numerator: 1, Denominator: 2,
+			ResetSchedule: &VestingSchedule{CliffMonths: 12, VestingMonths: 12, VestingType: "linear"}})},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err == nil {
+				t.Error("Expected change to be rejected")
+			}
+		})
+	}
+	if len(employee.Repricings) != 1 || original.Exchange != nil {
+		t.Error("Rejected change was recorded")
+	}
+}
+
+func TestLifecycleExchange(t *testing.T) {
+	service := NewVestingService()
+	store := NewMemoryEventStore()
+
+	for _, event := range []LifecycleEvent{
+		{Type: EventHired, EmployeeID: "emp001", EffectiveDate: date(2021, 1, 1)},
+		{Type: EventGrantIssued, EmployeeID: "emp001", Units: 10000, StrikePrice: 4,
+			Schedule: VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"}, EffectiveDate: date(2021, 1, 1)},
+		{Type: EventGrantRepriced, EmployeeID: "emp001", StrikePrice: 2.5, EffectiveDate: date(2022, 6, 1)},
+		{Type: EventGrantExchanged, EmployeeID: "emp001", EffectiveDate: date(2023, 1, 1),
+			Exchange: &GrantExchange{Numerator: 1, Denominator: 2, StrikePrice: 1}},
+	} {
+		if _, err := service.RecordEvent(store, event); err != nil {
+			t.Fatalf("RecordEvent failed: %v", err)
+		}
+	}
+
+	state, err := service.ReplayEmployee(store, "", "emp001", date(2023, 1, 2))
+	if err != nil {
+		t.Fatalf("ReplayEmployee failed: %v", err)
+	}
+	if state.Result.VestedUnits != 1805 || state.Result.StrikePrice != 1 {
+		t.Errorf("Expected 1805 units at 1.00 after the exchange, got %d at %.2f", state.Result.VestedUnits, state.Result.StrikePrice)
+	}
+
+	// A partly exercised grant cannot be exchanged
+	exercised := NewMemoryEventStore()
+	for _, event := range []LifecycleEvent{
+		{Type: EventHired, EmployeeID: "emp002", EffectiveDate: date(2021, 1, 1)},
+		{Type: EventGrantIssued, EmployeeID: "emp002", Units: 10000, StrikePrice: 4,
+			Schedule: VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"}, EffectiveDate: date(2021, 1, 1)},
+		{Type: EventExercised, EmployeeID: "emp002", Units: 1000, EffectiveDate: date(2022, 6, 1)},
+	} {
+		if _, err := service.RecordEvent(exercised, event); err != nil {
+			t.Fatalf("RecordEvent failed: %v", err)
+		}
+	}
+	if _, err := service.RecordEvent(exercised, LifecycleEvent{Type: EventGrantExchanged, EmployeeID: "emp002", EffectiveDate: date(2023, 1, 1),
+		Exchange: &GrantExchange{Numerator: 1, Denominator: 2, StrikePrice: 1}}); err == nil {
+		t.Error("Expected exchange of an exercised grant to be rejected")
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// ExpensePeriod is the stock comp expense booked for one service month
+type ExpensePeriod struct {
+	// Month is the 1-based service month counted from the vesting
+	// commencement date of the grant in force
+	Month             int
+	PeriodStart       time.Time
+	PeriodEnd         time.Time
+	Expense           float64
+	CumulativeExpense float64
+
+	// Forfeiture marks the termination month, whose expense includes the
+	// reversal of expense booked for units that will never vest
+	Forfeiture bool
+}
+
+// ExpenseSchedule is the expense attribution for a single grant
+type ExpenseSchedule struct {
+	EmployeeID       string
+	Method           string
+	FairValuePerUnit float64
+	GrantFairValue   float64
+	Periods          []ExpensePeriod
+	TotalExpense     float64
+
+	// ReversedExpense is the previously attributed expense taken back at
+	// termination for forfeited units
+	ReversedExpense float64
+}
+
+// MonthlyExpense is the company-wide expense for a calendar month
+type MonthlyExpense struct {
+	Month   time.Time
+	Expense float64
+}
+
+// ExpenseReport is the expense attribution for a batch of grants
+type ExpenseReport struct {
+	Method        string
+	Schedules     []ExpenseSchedule
+	MonthlyTotals []MonthlyExpense
+	TotalExpense  float64
+}
+
+// CalculateExpense attributes the grant-date fair value of an employee's
+// grant over its service period. method is "straight-line", which books the
+// grant evenly but never less than the value vested so far, or "graded",
+// which expenses each vest tranche over its own service period. Vesting that
+// stops at termination reverses the expense booked for unvested units.
+func (vs *VestingService) CalculateExpense(employee Employee, fairValuePerUnit float64, method string) (ExpenseSchedule, error) {
+	if employee.TotalUnits <= 0 {
+		return ExpenseSchedule{}, fmt.Errorf("invalid total units: %d", employee.TotalUnits)
+	}
+	if fairValuePerUnit < 0 || math.IsNaN(fairValuePerUnit) || math.IsInf(fairValuePerUnit, 0) {
+		return ExpenseSchedule{}, fmt.Errorf("invalid fair value per unit: %f", fairValuePerUnit)
+	}
+	if method != "straight-line" && method != "graded" {
+		return ExpenseSchedule{}, fmt.Errorf("invalid expense method: %s", method)
+	}
+	if err := validateTermChanges(employee); err != nil {
+		return ExpenseSchedule{}, err
+	}
+
+	// The grant in force after any exchange is expensed on its converted
+	// units, with its tranches moved by any amendments. The incremental
+	// value of an exchange is not measured.
+	employee = vs.applyTenantSettings(employee)
+	grant, conversion := grantInForce(employee)
+	grant = vestingStart(grant)
+	ends, vested := monthlyVesting(grant, conversion)
+	serviceMonths := len(vested) - 1
+
+	// The month the termination date falls in, and the units vested by then
+	stopMonth, keptUnits := serviceMonths, 0
+	if !employee.TerminationDate.IsZero() {
+		for stopMonth > 0 && !ends[stopMonth-1].Before(employee.TerminationDate) {
+			stopMonth--
+		}
+		if events := vestEvents(grant); len(events) > 0 {
+			keptUnits = conversion.Units(events[len(events)-1].CumulativeUnits)
+		}
+	}
+
+	schedule := ExpenseSchedule{
+		EmployeeID:       employee.ID,
+		Method:           method,
+		FairValuePerUnit: fairValuePerUnit,
+		GrantFairValue:   float64(conversion.Units(grant.TotalUnits)) * fairValuePerUnit,
+	}
+
+	cumulative := func(month int) float64 {
+		if method == "straight-line" {
+			return math.Max(schedule.GrantFairValue*float64(month)/float64(serviceMonths),
+				float64(vested[month])*fairValuePerUnit)
+		}
+		total := 0.0
+		for tranche := 1; tranche <= serviceMonths; tranche++ {
+			units := vested[tranche] - vested[tranche-1]
+			total += float64(units) * fairValuePerUnit * float64(min(month, tranche)) / float64(tranche)
+		}
+		return total
+	}
+
+	// Cumulative amounts are rounded to cents and each period books the
+	// difference, so the periods always add up to the final total
+	booked := 0.0
+	for month := 1; month <= stopMonth; month++ {
+		periodStart, periodEnd := ends[month-1], ends[month]
+		target := cumulative(month)
+		forfeiture := month == stopMonth && stopMonth < serviceMonths
+		if forfeiture {
+			// Only the units vested at termination keep their expense
+			schedule.ReversedExpense = roundCents(target - float64(keptUnits)*fairValuePerUnit)
+			target = float64(keptUnits) * fairValuePerUnit
+		}
+		target = roundCents(target)
+
+		schedule.Periods = append(schedule.Periods, ExpensePeriod{
+			Month:             month,
+			PeriodStart:       periodStart,
+			PeriodEnd:         periodEnd,
+			Expense:           roundCents(target - booked),
+			CumulativeExpense: target,
+			Forfeiture:        forfeiture,
+		})
+		booked = target
+	}
+
+	schedule.TotalExpense = booked
+	return schedule, nil
+}
+
+// grantInForce returns the grant that vests to the end, which is the
+// replacement once the employee's grant is exchanged, and the ratio its
+// units convert at
+func grantInForce(employee Employee) (Employee, SplitBasis) {
+	if employee.Exchange != nil && !terminatedBy(employee, employee.Exchange.EffectiveDate) {
+		return replacementGrant(employee)
+	}
+	employee.Exchange = nil
+	return employee, grantBasis
+}
+
+// monthlyVesting returns the end of each service month of the grant, with
+// ends[0] its start date, and the units converted at conversion that vest,
+// ignoring termination, before each end. The units never exceed the grant
+// and the months run to the last in which units vest.
+func monthlyVesting(grant Employee, conversion SplitBasis) (ends []time.Time, vested []int) {
+	grant.TerminationDate = time.Time{}
+	events := vestEvents(grant)
+	total := conversion.Units(grant.TotalUnits)
+
+	ends, vested = []time.Time{grant.StartDate}, []int{0}
+	for month, next := 1, 0; next < len(events); month++ {
+		end := nextBoundary(grant.StartDate, ends[month-1], month, grant.Schedule.VestDay)
+		units := vested[month-1]
+		for ; next < len(events) && events[next].Date.Before(end); next++ {
+			units = min(conversion.Units(events[next].CumulativeUnits), total)
+		}
+		ends, vested = append(ends, end), append(vested, units)
+	}
+	return ends, vested
+}
+
+// AttributeExpense calculates expense schedules for every employee using the
+// fair value per unit keyed by employee ID, falling back to the value
+// attached to the grant by ValueGrant, and totals them by the calendar month
+// in which each service period ends
+func (vs *VestingService) AttributeExpense(employees []Employee, fairValues map[string]float64, method string) (ExpenseReport, error) {
+	report := ExpenseReport{Method: method}
+	monthly := make(map[time.Time]float64)
+
+	for _, employee := range employees {
+		fairValue, exists := fairValues[employee.ID]
+		if !exists && employee.FairValuePerUnit > 0 {
+			fairValue, exists = employee.FairValuePerUnit, true
+		}
+		if !exists {
+			return ExpenseReport{}, fmt.Errorf("fair value not found for employee %s", employee.ID)
+		}
+
+		schedule, err := vs.CalculateExpense(employee, fairValue, method)
+		if err != nil {
+			return ExpenseReport{}, fmt.Errorf("employee %s: %w", employee.ID, err)
+		}
+
+		for _, period := range schedule.Periods {
+			end := period.PeriodEnd
+			month := time.Date(end.Year(), end.Month(), 1, 0, 0, 0, 0, end.Location())
+			monthly[month] = roundCents(monthly[month] + period.Expense)
+		}
+		report.Schedules = append(report.Schedules, schedule)
+		report.TotalExpense = roundCents(report.TotalExpense + schedule.TotalExpense)
+	}
+
+	for month, expense := range monthly {
+		report.MonthlyTotals = append(report.MonthlyTotals, MonthlyExpense{Month: month, Expense: expense})
+	}
+	sort.Slice(report.MonthlyTotals, func(i, j int) bool {
+		return report.MonthlyTotals[i].Month.Before(report.MonthlyTotals[j].Month)
+	})
+
+	return report, nil
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCalculateExpense(t *testing.T) {
+	service := NewVestingService()
+
+	employee := Employee{
+		ID:         "expense1",
+		Name:       "Expense Employee",
+		StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		TotalUnits: 36000,
+		Schedule: VestingSchedule{
+			CliffMonths:   12,
+			VestingMonths: 36,
+			VestingType:   "linear",
+		},
+	}
+
+	terminated := employee
+	terminated.TerminationDate = time.Date(2022, 7, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		employee      Employee
+		method        string
+		periods       int
+		firstExpense  float64
+		totalExpense  float64
+		reversed      float64
+		lastForfeited bool
+	}{
+		{
+			name:         "Straight-line",
+			employee:     employee,
+			method:       "straight-line",
+			periods:      36,
+			firstExpense: 2000,
+			totalExpense: 72000,
+		},
+		{
+			name:         "Graded",
+			employee:     employee,
+			method:       "graded",
+			periods:      36,
+			firstExpense: gradedFirstMonth(13, 36, 3000),
+			totalExpense: 72000,
+		},
+		{
+			name:          "Straight-line with forfeiture",
+			employee:      terminated,
+			method:        "straight-line",
+			periods:       19,
+			firstExpense:  2000,
+			totalExpense:  21000,
+			reversed:      17000,
+			lastForfeited: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := service.CalculateExpense(tt.employee, 2, tt.method)
+			if err != nil {
+				t.Fatalf("CalculateExpense failed: %v", err)
+			}
+
+			if len(schedule.Periods) != tt.periods {
+				t.Fatalf("Expected %d periods, got %d", tt.periods, len(schedule.Periods))
+			}
+			if schedule.Periods[0].Expense != tt.firstExpense {
+				t.Errorf("Expected first month expense %.2f, got %.2f", tt.firstExpense, schedule.Periods[0].Expense)
+			}
+			if schedule.TotalExpense != tt.totalExpense {
+				t.Errorf("Expected total expense %.2f, got %.2f", tt.totalExpense, schedule.TotalExpense)
+			}
+			if schedule.ReversedExpense != tt.reversed {
+				t.Errorf("Expected reversed expense %.2f, got %.2f", tt.reversed, schedule.ReversedExpense)
+			}
+
+			sum := 0.0
+			for _, period := range schedule.Periods {
+				sum += period.Expense
+			}
+			if math.Abs(sum-schedule.TotalExpense) > 0.001 {
+				t.Errorf("Periods sum to %.2f, expected %.2f", sum, schedule.TotalExpense)
+			}
+
+			last := schedule.Periods[len(schedule.Periods)-1]
+			if last.Forfeiture != tt.lastForfeited {
+				t.Errorf("Expected last period forfeiture %v", tt.lastForfeited)
+			}
+		})
+	}
+
+	if _, err := service.CalculateExpense(employee, 2, "accelerated"); err == nil {
+		t.Error("Expected error for an unknown method")
+	}
+	if _, err := service.CalculateExpense(employee, -1, "graded"); err == nil {
+		t.Error("Expected error for a negative fair value")
+	}
+}
+
+func TestAttributeExpense(t *testing.T) {
+	service := NewVestingService()
+
+	employees := []Employee{
+		{
+			ID:         "expense_a",
+			StartDate:  time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+			TotalUnits: 12000,
+			Schedule:   VestingSchedule{CliffMonths: 0, VestingMonths: 12, VestingType: "linear"},
+		},
+		{
+			ID:         "expense_b",
+			StartDate:  time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC),
+			TotalUnits: 6000,
+			Schedule:   VestingSchedule{CliffMonths: 0, VestingMonths: 6, VestingType: "linear"},
+		},
+	}
+
+	report, err := service.AttributeExpense(employees, map[string]float64{"expense_a": 1, "expense_b": 3}, "straight-line")
+	if err != nil {
+		t.Fatalf("AttributeExpense failed: %v", err)
+	}
+
+	if report.TotalExpense != 30000 {
+		t.Errorf("Expected total expense 30000, got %.2f", report.TotalExpense)
+	}
+	if len(report.MonthlyTotals) != 12 {
+		t.Fatalf("Expected 12 monthly totals, got %d", len(report.MonthlyTotals))
+	}
+
+	// Service months end on the 1st, so January's service lands in February
+	if report.MonthlyTotals[0].Month != time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC) || report.MonthlyTotals[0].Expense != 1000 {
+		t.Errorf("Unexpected first month %+v", report.MonthlyTotals[0])
+	}
+	if report.MonthlyTotals[6].Expense != 4000 {
+		t.Errorf("Expected 4000 once both grants are expensing, got %.2f", report.MonthlyTotals[6].Expense)
+	}
+
+	if _, err := service.AttributeExpense(employees, map[string]float64{"expense_a": 1}, "graded"); err == nil {
+		t.Error("Expected error for a missing fair value")
+	}
+}
+
+// gradedFirstMonth is the first month of graded expense for equal monthly
+// tranches of value vesting in months first through last
+func gradedFirstMonth(first, last int, value float64) float64 {
+	total := 0.0
+	for month := first; month <= last; month++ {
+		total += value / float64(month)
+	}
+	return roundCents(total)
+}
+
+func TestExpenseFollowsTermChanges(t *testing.T) {
+	service := NewVestingService()
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	original := Employee{
+		ID:         "changed",
+		StartDate:  start,
+		TotalUnits: 48000,
+		Schedule:   VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"},
+	}
+
+	reset := original
+	if err := reset.ExchangeGrant(GrantExchange{EffectiveDate: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC), Numerator: 1, Denominator: 2,
+		ResetSchedule: &VestingSchedule{CliffMonths: 0, VestingMonths: 24, VestingType: "linear"}}); err != nil {
+		t.Fatalf("ExchangeGrant failed: %v", err)
+	}
+	kept := original
+	if err := kept.ExchangeGrant(GrantExchange{EffectiveDate: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC), Numerator: 1, Denominator: 2}); err != nil {
+		t.Fatalf("ExchangeGrant failed: %v", err)
+	}
+	amended := original
+	if err := amended.AmendSchedule(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		VestingSchedule{CliffMonths: 0, VestingMonths: 60, VestingType: "linear"}); err != nil {
+		t.Fatalf("AmendSchedule failed: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		employee   Employee
+		periods    int
+		firstStart time.Time
+		total      float64
+	}{
+		// The replacement grant of 24,000 units vests over 24 months from
+		// the exchange
+		{"reset_exchange", reset, 24, time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC), 24000},
+		{"kept_vesting_exchange", kept, 48, start, 24000},
+		// The amendment spreads all 48,000 units over the 60 months after
+		// the first year
+		{"amended_to_72_months", amended, 72, start, 48000},
+	}
+
+	for _, tt := range tests {
+		for _, method := range []string{"straight-line", "graded"} {
+			t.Run(tt.name+"_"+method, func(t *testing.T) {
+				schedule, err := service.CalculateExpense(tt.employee, 1, method)
+				if err != nil {
+					t.Fatalf("CalculateExpense failed: %v", err)
+				}
+				if len(schedule.Periods) != tt.periods || !schedule.Periods[0].PeriodStart.Equal(tt.firstStart) {
+					t.Fatalf("Expected %d periods from %s, got %d from %s", tt.periods, tt.firstStart.Format("2006-01-02"),
+						len(schedule.Periods), schedule.Periods[0].PeriodStart.Format("2006-01-02"))
+				}
+				if schedule.GrantFairValue != tt.total || schedule.TotalExpense != tt.total {
+					t.Errorf("Expected %.2f expensed, got %.2f of %.2f", tt.total, schedule.TotalExpense, schedule.GrantFairValue)
+				}
+
+				// Once the grant in force has taken over, expense keeps up with
+				// the units calculateVesting reports
+				for i, period := range schedule.Periods {
+					result, err := service.calculateVesting(tt.employee, period.PeriodEnd)
+					if err != nil {
+						t.Fatalf("calculateVesting failed: %v", err)
+					}
+					if units := result.VestedUnits + result.UnvestedUnits; float64(units) != tt.total {
+						if i == len(schedule.Periods)-1 {
+							t.Fatalf("calculateVesting reports %d units, expected %.0f", units, tt.total)
+						}
+						continue
+					}
+					if period.CumulativeExpense < float64(result.VestedUnits)-0.01 {
+						t.Fatalf("Month %d: %.2f expensed for %d vested units", period.Month, period.CumulativeExpense, result.VestedUnits)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestExpenseNeverExceedsGrant(t *testing.T) {
+	service := NewVestingService()
+
+	// The sample backloaded grant, whose years run from the cliff
+	employee := Employee{
+		ID:         "emp002",
+		StartDate:  time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+		TotalUnits: 60000,
+		Schedule:   VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "backloaded"},
+	}
+
+	for _, method := range []string{"straight-line", "graded"} {
+		t.Run(method, func(t *testing.T) {
+			schedule, err := service.CalculateExpense(employee, 1, method)
+			if err != nil {
+				t.Fatalf("CalculateExpense failed: %v", err)
+			}
+			if len(schedule.Periods) != 48 || schedule.TotalExpense != 60000 {
+				t.Errorf("Expected 60000.00 over 48 periods, got %.2f over %d", schedule.TotalExpense, len(schedule.Periods))
+			}
+			for _, period := range schedule.Periods {
+				if period.Expense < 0 || period.CumulativeExpense > schedule.GrantFairValue {
+					t.Errorf("Month %d books %.2f, %.2f cumulative of %.2f", period.Month, period.Expense,
+						period.CumulativeExpense, schedule.GrantFairValue)
+				}
+			}
+		})
+	}
+}
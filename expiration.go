@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Default option terms, used when neither the employee nor the tenant sets
+// their own
+const (
+	DefaultContractualTermMonths = 120
+	DefaultExerciseWindowDays    = 90
+)
+
+// ExpiringGrant is an employee whose option grant expires soon, with the
+// units that can still be exercised before it does
+type ExpiringGrant struct {
+	EmployeeID       string
+	ExpirationDate   time.Time
+	DaysRemaining    int
+	ExercisableUnits int
+
+	// PostTermination is set when the grant expires at the end of the
+	// exercise window after termination rather than its contractual term
+	PostTermination bool
+}
+
+// validateOptionTerms ensures the employee's option terms are not negative
+func validateOptionTerms(employee Employee) error {
+	if employee.ContractualTermMonths < 0 {
+		return fmt.Errorf("invalid contractual term: %d months", employee.ContractualTermMonths)
+	}
+	if employee.ExerciseWindowDays < 0 {
+		return fmt.Errorf("invalid exercise window: %d days", employee.ExerciseWindowDays)
+	}
+	return nil
+}
+
+// expirationDate returns the date the grant's vested units expire: the end
+// of its contractual term, or of the exercise window after termination when
+// that is earlier, which is reported as postTermination. RSUs have no strike
+// price and never expire, so the date is zero for them.
+func expirationDate(grant Employee) (expiration time.Time, postTermination bool) {
+	if grant.StrikePrice <= 0 {
+		return time.Time{}, false
+	}
+
+	termMonths := grant.ContractualTermMonths
+	if termMonths == 0 {
+		termMonths = DefaultContractualTermMonths
+	}
+	expiration = grantDate(grant).AddDate(0, termMonths, 0)
+
+	if !grant.TerminationDate.IsZero() {
+		windowDays := grant.ExerciseWindowDays
+		if windowDays == 0 {
+			windowDays = DefaultExerciseWindowDays
+		}
+		if closes := grant.TerminationDate.AddDate(0, 0, windowDays); closes.Before(expiration) {
+			return closes, true
+		}
+	}
+	return expiration, false
+}
+
+// ExpiringWindows returns the default tenant's employees last passed to
+// ProcessBatch whose option grants expire in the days after asOfDate,
+// starting with asOfDate itself, and still have units to exercise. Grants
+// are ordered by expiration date, then employee ID.
+func (vs *VestingService) ExpiringWindows(asOfDate time.Time, days int) ([]ExpiringGrant, error) {
+	return vs.expiringWindows(defaultTenant, asOfDate, days)
+}
+
+func (vs *VestingService) expiringWindows(tenantID string, asOfDate time.Time, days int) ([]ExpiringGrant, error) {
+	if days <= 0 {
+		return nil, fmt.Errorf("invalid number of days: %d", days)
+	}
+
+	vs.mu.Lock()
+	employees := make([]Employee, 0, len(vs.cache.employees))
+	for key, employee := range vs.cache.employees {
+		if key.TenantID == tenantID {
+			employees = append(employees, employee)
+		}
+	}
+	vs.mu.Unlock()
+
+	end := asOfDate.AddDate(0, 0, days)
+	var expiring []ExpiringGrant
+
+	for _, employee := range employees {
+		result, err := vs.calculateVesting(employee, asOfDate)
+		if err != nil {
+			return nil, fmt.Errorf("employee %s: %w", employee.ID, err)
+		}
+		if result.ExpirationDate.IsZero() || result.ExercisableUnits == 0 || !result.ExpirationDate.Before(end) {
+			continue
+		}
+
+		grant, _ := grantAt(vs.applyTenantSettings(employee), result.AsOfDate)
+		_, postTermination := expirationDate(grant)
+		expiring = append(expiring, ExpiringGrant{
+			EmployeeID:       employee.ID,
+			ExpirationDate:   result.ExpirationDate,
+			DaysRemaining:    daysBetween(asOfDate, result.ExpirationDate),
+			ExercisableUnits: result.ExercisableUnits,
+			PostTermination:  postTermination,
+		})
+	}
+
+	sort.Slice(expiring, func(i, j int) bool {
+		if !expiring[i].ExpirationDate.Equal(expiring[j].ExpirationDate) {
+			return expiring[i].ExpirationDate.Before(expiring[j].ExpirationDate)
+		}
+		return expiring[i].EmployeeID < expiring[j].EmployeeID
+	})
+	return expiring, nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOptionExpiration(t *testing.T) {
+	service := NewVestingService()
+	if err := service.RegisterTenant("acme", TenantSettings{ExerciseWindowDays: 30}); err != nil {
+		t.Fatalf("RegisterTenant failed: %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		modify           func(*Employee)
+		asOfDate         time.Time
+		exercisableUnits int
+		expiredUnits     int
+		expirationDate   time.Time
+	}{
+		{"within_term", nil, date(2023, 12, 31), 4800, 0, date(2024, 1, 1)},
+		{"term_ended", nil, date(2024, 1, 1), 0, 4800, date(2024, 1, 1)},
+		{"custom_term", func(e *Employee) { e.ContractualTermMonths = 60 }, date(2019, 1, 1), 0, 4800, date(2019, 1, 1)},
+		// 24 months: 1600 units, exercisable for 90 days after termination
+		{"window_open", func(e *Employee) { e.TerminationDate = date(2016, 1, 1) }, date(2016, 3, 30), 1600, 0, date(2016, 3, 31)},
+		{"window_closed", func(e *Employee) { e.TerminationDate = date(2016, 1, 1) }, date(2016, 3, 31), 0, 1600, date(2016, 3, 31)},
+		{"extended_window", func(e *Employee) {
+			e.TerminationDate = date(2016, 1, 1)
+			e.ExerciseWindowDays = 730
+		}, date(2017, 6, 1), 1600, 0, date(2017, 12, 31)},
+		{"window_capped_by_term", func(e *Employee) {
+			e.TerminationDate = date(2016, 1, 1)
+			e.ExerciseWindowDays = 3650
+		}, date(2024, 1, 1), 0, 1600, date(2024, 1, 1)},
+		{"tenant_window", func(e *Employee) {
+			e.TenantID = "acme"
+			e.TerminationDate = date(2016, 1, 1)
+		}, date(2016, 1, 31), 0, 1600, date(2016, 1, 31)},
+		{"rsu_never_expires", func(e *Employee) { e.StrikePrice = 0 }, date(2030, 1, 1), 4800, 0, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			employee := Employee{
+				ID:          "emp001",
+				StartDate:   date(2014, 1, 1),
+				TotalUnits:  4800,
+				Schedule:    VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"},
+				StrikePrice: 1,
+			}
+			if tt.modify != nil {
+				tt.modify(&employee)
+			}
+
+			result, err := service.calculateVesting(employee, tt.asOfDate)
+			if err != nil {
+				t.Fatalf("calculateVesting failed: %v", err)
+			}
+			if result.ExercisableUnits != tt.exercisableUnits || result.ExpiredUnits != tt.expiredUnits {
+				t.Errorf("Expected %d exercisable and %d expired units, got %d and %d",
+					tt.exercisableUnits, tt.expiredUnits, result.ExercisableUnits, result.ExpiredUnits)
+			}
+			if !result.ExpirationDate.Equal(tt.expirationDate) {
+				t.Errorf("Expected expiration %s, got %s", tt.expirationDate.Format("2006-01-02"), result.ExpirationDate.Format("2006-01-02"))
+			}
+			if result.ExercisableUnits+result.ExpiredUnits != result.VestedUnits {
+				t.Errorf("Exercisable and expired units do not add up to %d vested", result.VestedUnits)
+			}
+		})
+	}
+
+	invalid := Employee{ID: "emp001", StartDate: date(2014, 1, 1), TotalUnits: 4800, StrikePrice: 1,
+		Schedule: VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"}, ExerciseWindowDays: -1}
+	if _, err := service.calculateVesting(invalid, date(2016, 1, 1)); err == nil {
+		t.Error("Expected a negative exercise window to be rejected")
+	}
+	if err := service.RegisterTenant("globex", TenantSettings{ContractualTermMonths: -12}); err == nil {
+		t.Error("Expected a negative tenant contractual term to be rejected")
+	}
+}
+
+func TestExpiringWindows(t *testing.T) {
+	service := NewVestingService()
+	schedule := VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"}
+	employees := []Employee{
+		{ID: "emp001", StartDate: date(2014, 1, 1), TotalUnits: 4800, Schedule: schedule, StrikePrice: 1, TerminationDate: date(2016, 1, 1)},
+		{ID: "emp002", StartDate: date(2006, 4, 1), TotalUnits: 4800, Schedule: schedule, StrikePrice: 1},
+		{ID: "emp003", StartDate: date(2006, 4, 1), TotalUnits: 4800, Schedule: schedule},
+		{ID: "emp004", StartDate: date(2014, 1, 1), TotalUnits: 4800, Schedule: schedule, StrikePrice: 1, TerminationDate: date(2015, 6, 1)},
+		{ID: "emp005", StartDate: date(2015, 6, 1), TotalUnits: 4800, Schedule: schedule, StrikePrice: 1, TerminationDate: date(2016, 1, 1)},
+	}
+	asOfDate := date(2016, 3, 1)
+	if err := service.ProcessBatch(employees, asOfDate); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+
+	expiring, err := service.ExpiringWindows(asOfDate, 60)
+	if err != nil {
+		t.Fatalf("ExpiringWindows failed: %v", err)
+	}
+	expected := []ExpiringGrant{
+		{EmployeeID: "emp001", ExpirationDate: date(2016, 3, 31), DaysRemaining: 30, ExercisableUnits: 1600, PostTermination: true},
+		{EmployeeID: "emp002", ExpirationDate: date(2016, 4, 1), DaysRemaining: 31, ExercisableUnits: 4800},
+	}
+	if len(expiring) != len(expected) {
+		t.Fatalf("Expected %d expiring grants, got %+v", len(expected), expiring)
+	}
+	for i := range expected {
+		if expiring[i] != expected[i] {
+			t.Errorf("Grant %d: expected %+v, got %+v", i, expected[i], expiring[i])
+		}
+	}
+
+	if expiring, _ := service.ExpiringWindows(asOfDate, 30); len(expiring) != 0 {
+		t.Errorf("Expected no windows closing within 30 days, got %+v", expiring)
+	}
+	if _, err := service.ExpiringWindows(asOfDate, 0); err == nil {
+		t.Error("Expected an invalid number of days to be rejected")
+	}
+}
+
+func TestLifecycleExerciseAfterExpiration(t *testing.T) {
+	service := NewVestingService()
+	store := NewMemoryEventStore()
+
+	for _, event := range []LifecycleEvent{
+		{Type: EventHired, EmployeeID: "emp001", EffectiveDate: date(2014, 1, 1)},
+		{Type: EventGrantIssued, EmployeeID: "emp001", Units: 4800, StrikePrice: 1, ExerciseWindowDays: 30,
+			Schedule: VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"}, EffectiveDate: date(2014, 1, 1)},
+		{Type: EventTerminated, EmployeeID: "emp001", EffectiveDate: date(2016, 1, 1)},
+		{Type: EventExercised, EmployeeID: "emp001", Units: 600, EffectiveDate: date(2016, 1, 20)},
+	} {
+		if _, err := service.RecordEvent(store, event); err != nil {
+			t.Fatalf("RecordEvent failed: %v", err)
+		}
+	}
+
+	state, err := service.ReplayEmployee(store, "", "emp001", date(2016, 2, 1))
+	if err != nil {
+		t.Fatalf("ReplayEmployee failed: %v", err)
+	}
+	if state.ExercisableUnits != 0 || state.Result.ExpiredUnits != 1600 {
+		t.Errorf("Expected the window to have closed on 1600 units, got %d exercisable and %d expired",
+			state.ExercisableUnits, state.Result.ExpiredUnits)
+	}
+
+	if _, err := service.RecordEvent(store, LifecycleEvent{Type: EventExercised, EmployeeID: "emp001", Units: 100,
+		EffectiveDate: date(2016, 2, 1)}); err == nil {
+		t.Error("Expected exercise after the window closed to be rejected")
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// VestingExplanation records each step calculateVesting took to reach a
+// result. It marshals to JSON as-is and String renders it as text.
+type VestingExplanation struct {
+	EmployeeID    string    `json:"employee_id"`
+	StartDate     time.Time `json:"start_date"`
+	AsOfDate      time.Time `json:"as_of_date"`
+	TotalUnits    int       `json:"total_units"`
+	VestingType   string    `json:"vesting_type"`
+	CliffMonths   int       `json:"cliff_months"`
+	VestingMonths int       `json:"vesting_months"`
+
+	// CommencementDate is set when the grant vests from a date other than
+	// StartDate, the hire date. Months are then counted from it.
+	CommencementDate time.Time `json:"commencement_date,omitzero"`
+
+	// AmendedOn is the effective date of the schedule amendment in force,
+	// if any. The schedule fields are then the amended terms, months are
+	// counted from AmendedOn and the percentages apply to the units that had
+	// not vested by then.
+	AmendedOn            time.Time `json:"amended_on,omitzero"`
+	UnitsBeforeAmendment int       `json:"units_before_amendment,omitempty"`
+
+	// ExchangedOn is the effective date of the grant's exchange, if it has
+	// been exchanged. The figures are then those of the replacement grant;
+	// when it kept the old vesting they are on the old grant's units, which
+	// convert at ConversionRatio.
+	ExchangedOn     time.Time `json:"exchanged_on,omitzero"`
+	ConversionRatio string    `json:"conversion_ratio,omitempty"`
+
+	// MonthsEmployed is the months of the schedule in force started by the
+	// vesting cutoff, as counted by countMonths from the commencement date or
+	// from AmendedOn
+	MonthsEmployed int  `json:"months_employed"`
+	CliffReached   bool `json:"cliff_reached"`
+
+	// MonthsVested counts the months after the cliff that were applied
+	MonthsVested int `json:"months_vested"`
+
+	// UnitsPerMonth is only set for linear schedules
+	UnitsPerMonth float64 `json:"units_per_month,omitempty"`
+
+	// YearPercentages lists the backloaded percentages in the order applied
+	YearPercentages []YearPercentage `json:"year_percentages,omitempty"`
+
+	VestedPercent  float64 `json:"vested_percent"`
+	UnroundedUnits float64 `json:"unrounded_units"`
+	Rounding       string  `json:"rounding"`
+	Remainder      string  `json:"remainder,omitempty"`
+
+	// VestedFraction and ExactUnits are the exact values VestedPercent and
+	// UnroundedUnits are displayed from, as fractions such as "13/36"
+	VestedFraction string `json:"vested_fraction,omitempty"`
+	ExactUnits     string `json:"exact_units,omitempty"`
+
+	// SplitRatio is set when the vested and unvested units are restated
+	// after a stock split. The other figures are on the grant's basis.
+	SplitRatio string `json:"split_ratio,omitempty"`
+
+	VestedUnits   int       `json:"vested_units"`
+	UnvestedUnits int       `json:"unvested_units"`
+	NextVestDate  time.Time `json:"next_vest_date,omitzero"`
+
+	// ExpirationDate is only set for option grants
+	ExpirationDate time.Time `json:"expiration_date,omitzero"`
+	ExpiredUnits   int       `json:"expired_units,omitempty"`
+}
+
+// YearPercentage is one backloaded year's contribution to the vested percent
+type YearPercentage struct {
+	Year          int     `json:"year"`
+	Percent       float64 `json:"percent"`
+	MonthsApplied int     `json:"months_applied"`
+	Applied       float64 `json:"applied"`
+}
+
+// addYear records a backloaded year contribution, doing nothing when no
+// explanation was requested
+func (e *VestingExplanation) addYear(year int, percent *big.Rat, months int, applied *big.Rat) {
+	if e == nil {
+		return
+	}
+	e.YearPercentages = append(e.YearPercentages, YearPercentage{
+		Year:          year,
+		Percent:       ratFloat(percent) * 100,
+		MonthsApplied: months,
+		Applied:       ratFloat(applied) * 100,
+	})
+}
+
+// setFraction records the exact vested fraction of the grant and the units
+// it comes to before rounding
+func (e *VestingExplanation) setFraction(fraction, units *big.Rat) {
+	e.VestedPercent = ratFloat(new(big.Rat).Mul(fraction, big.NewRat(100, 1)))
+	e.UnroundedUnits = ratFloat(units)
+	e.VestedFraction = fraction.RatString()
+	e.ExactUnits = units.RatString()
+}
+
+// finish copies the final figures from result, doing nothing when no
+// explanation was requested
+func (e *VestingExplanation) finish(result VestingResult) {
+	if e == nil {
+		return
+	}
+	e.VestedUnits = result.VestedUnits
+	e.UnvestedUnits = result.UnvestedUnits
+	e.NextVestDate = result.NextVestDate
+	e.ExpirationDate = result.ExpirationDate
+	e.ExpiredUnits = result.ExpiredUnits
+	if result.Basis != grantBasis {
+		e.SplitRatio = result.Basis.String()
+	}
+}
+
+// String renders the explanation as a human-readable breakdown
+func (e *VestingExplanation) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Vesting explanation for %s as of %s\n", e.EmployeeID, e.AsOfDate.Format("2006-01-02"))
+	fmt.Fprintf(&b, "  Schedule: %s, %d month cliff, %d months total, %d units\n",
+		e.VestingType, e.CliffMonths, e.VestingMonths, e.TotalUnits)
+	if !e.ExchangedOn.IsZero() {
+		fmt.Fprintf(&b, "  Exchanged: %s at %s\n", e.ExchangedOn.Format("2006-01-02"), e.ConversionRatio)
+	}
+	if !e.AmendedOn.IsZero() {
+		fmt.Fprintf(&b, "  Amended: %s, %d units vested before\n", e.AmendedOn.Format("2006-01-02"), e.UnitsBeforeAmendment)
+		fmt.Fprintf(&b, "  Months employed: %d (since amendment)\n", e.MonthsEmployed)
+	} else {
+		since := e.StartDate
+		if !e.CommencementDate.IsZero() {
+			since = e.CommencementDate
+		}
+		fmt.Fprintf(&b, "  Months employed: %d (since %s)\n", e.MonthsEmployed, since.Format("2006-01-02"))
+	}
+
+	if !e.CliffReached {
+		fmt.Fprintf(&b, "  Cliff: not reached (%d of %d months)\n", e.MonthsEmployed, e.CliffMonths)
+	} else {
+		fmt.Fprintf(&b, "  Cliff: reached, %d months vested after cliff\n", e.MonthsVested)
+		if e.VestingType == "linear" {
+			fmt.Fprintf(&b, "  Units per month: %.6f\n", e.UnitsPerMonth)
+		}
+		for _, year := range e.YearPercentages {
+			fmt.Fprintf(&b, "  Year %d: %.2f%% x %d/12 months = %.4f%%\n",
+				year.Year, year.Percent, year.MonthsApplied, year.Applied)
+		}
+		fmt.Fprintf(&b, "  Vested percent: %.4f%%\n", e.VestedPercent)
+		fmt.Fprintf(&b, "  Vested fraction: %s\n", e.VestedFraction)
+		fmt.Fprintf(&b, "  Unrounded units: %.6f = %s (rounding: %s)\n", e.UnroundedUnits, e.ExactUnits, e.Rounding)
+		if e.Remainder != "" {
+			fmt.Fprintf(&b, "  Rounding remainder vests with the %s tranche\n", e.Remainder)
+		}
+	}
+
+	if e.SplitRatio != "" {
+		fmt.Fprintf(&b, "  Stock split adjustment: %s\n", e.SplitRatio)
+	}
+	fmt.Fprintf(&b, "  Vested units: %d\n", e.VestedUnits)
+	fmt.Fprintf(&b, "  Unvested units: %d\n", e.UnvestedUnits)
+	if !e.NextVestDate.IsZero() {
+		fmt.Fprintf(&b, "  Next vest date: %s\n", e.NextVestDate.Format("2006-01-02"))
+	}
+	if e.ExpiredUnits > 0 {
+		fmt.Fprintf(&b, "  Expired: %s, %d vested units can no longer be exercised\n", e.ExpirationDate.Format("2006-01-02"), e.ExpiredUnits)
+	} else if !e.ExpirationDate.IsZero() {
+		fmt.Fprintf(&b, "  Expires: %s\n", e.ExpirationDate.Format("2006-01-02"))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExplainVesting(t *testing.T) {
+	service := NewVestingService()
+
+	t.Run("Linear", func(t *testing.T) {
+		employee := Employee{
+			ID:         "explain_linear",
+			Name:       "Linear Employee",
+			StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			TotalUnits: 10000,
+			Schedule: VestingSchedule{
+				CliffMonths:   12,
+				VestingMonths: 48,
+				VestingType:   "linear",
+			},
+		}
+		asOfDate := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		result, explain, err := service.ExplainVesting(employee, asOfDate)
+		if err != nil {
+			t.Fatalf("ExplainVesting failed: %v", err)
+		}
+
+		plain, err := service.calculateVesting(employee, asOfDate)
+		if err != nil {
+			t.Fatalf("calculateVesting failed: %v", err)
+		}
+		if result != plain {
+			t.Errorf("Explained result %+v differs from calculateVesting %+v", result, plain)
+		}
+
+		if explain.MonthsEmployed != 24 || !explain.CliffReached || explain.MonthsVested != 12 {
+			t.Errorf("Unexpected month breakdown: employed=%d cliff=%v vested=%d",
+				explain.MonthsEmployed, explain.CliffReached, explain.MonthsVested)
+		}
+		if explain.UnitsPerMonth != 10000.0/36.0 {
+			t.Errorf("Expected %f units per month, got %f", 10000.0/36.0, explain.UnitsPerMonth)
+		}
+		if explain.VestedUnits != result.VestedUnits || explain.UnvestedUnits != result.UnvestedUnits {
+			t.Error("Explanation final figures do not match the result")
+		}
+		if explain.Rounding != "floor" {
+			t.Errorf("Expected floor rounding, got %q", explain.Rounding)
+		}
+	})
+
+	t.Run("Backloaded", func(t *testing.T) {
+		employee := Employee{
+			ID:         "explain_backloaded",
+			Name:       "Backloaded Employee",
+			StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			TotalUnits: 40000,
+			Schedule: VestingSchedule{
+				CliffMonths:   12,
+				VestingMonths: 48,
+				VestingType:   "backloaded",
+			},
+		}
+
+		result, explain, err := service.ExplainVesting(employee, time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatalf("ExplainVesting failed: %v", err)
+		}
+
+		var applied float64
+		for _, year := range explain.YearPercentages {
+			applied += year.Applied
+		}
+		if len(explain.YearPercentages) == 0 || math.Abs(applied-explain.VestedPercent) > 1e-9 {
+			t.Errorf("Year percentages %+v do not add up to %f%%", explain.YearPercentages, explain.VestedPercent)
+		}
+		if explain.VestedUnits != result.VestedUnits {
+			t.Errorf("Expected %d vested units in explanation, got %d", result.VestedUnits, explain.VestedUnits)
+		}
+	})
+
+	t.Run("Before cliff", func(t *testing.T) {
+		employee := Employee{
+			ID:         "explain_cliff",
+			Name:       "Cliff Employee",
+			StartDate:  time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			TotalUnits: 48000,
+			Schedule: VestingSchedule{
+				CliffMonths:   12,
+				VestingMonths: 48,
+				VestingType:   "linear",
+			},
+		}
+
+		_, explain, err := service.ExplainVesting(employee, time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatalf("ExplainVesting failed: %v", err)
+		}
+		if explain.CliffReached {
+			t.Error("Expected cliff not to be reached")
+		}
+		if !strings.Contains(explain.String(), "Cliff: not reached (5 of 12 months)") {
+			t.Errorf("Text rendering missing cliff status:\n%s", explain)
+		}
+
+		data, err := json.Marshal(explain)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		var decoded VestingExplanation
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if decoded.MonthsEmployed != 5 || decoded.NextVestDate != explain.NextVestDate {
+			t.Errorf("JSON round trip lost data: %s", data)
+		}
+	})
+
+	t.Run("Invalid units", func(t *testing.T) {
+		_, explain, err := service.ExplainVesting(Employee{ID: "explain_bad"}, time.Now())
+		if err == nil || explain != nil {
+			t.Error("Expected an error and no explanation for invalid units")
+		}
+	})
+}
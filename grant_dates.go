@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// commencementDate returns the date the employee's grant vests from, which
+// is the hire date unless the grant has its own commencement date
+func commencementDate(employee Employee) time.Time {
+	if employee.VestingCommencementDate.IsZero() {
+		return employee.StartDate
+	}
+	return employee.VestingCommencementDate
+}
+
+// grantDate returns the date the employee's grant was made, which is the
+// hire date unless the grant has its own grant date
+func grantDate(employee Employee) time.Time {
+	if employee.GrantDate.IsZero() {
+		return employee.StartDate
+	}
+	return employee.GrantDate
+}
+
+// vestingStart returns the employee with StartDate moved to the vesting
+// commencement date, which is the date the schedule counts months from
+func vestingStart(employee Employee) Employee {
+	employee.StartDate = commencementDate(employee)
+	return employee
+}
+
+// validateGrantDates ensures a grant is not made before the board approved
+// it
+func validateGrantDates(employee Employee) error {
+	if employee.BoardApprovalDate.IsZero() || employee.GrantDate.IsZero() {
+		return nil
+	}
+	if employee.GrantDate.Before(employee.BoardApprovalDate) {
+		return fmt.Errorf("grant date %s is before board approval on %s",
+			employee.GrantDate.Format("2006-01-02"), employee.BoardApprovalDate.Format("2006-01-02"))
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVestingCommencementDate(t *testing.T) {
+	service := NewVestingService()
+	schedule := VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"}
+
+	tests := []struct {
+		name             string
+		hireDate         time.Time
+		commencementDate time.Time
+		asOfDate         time.Time
+		vestedUnits      int
+		nextVestDate     time.Time
+		firstVest        time.Time
+	}{
+		// Vesting commenced a year before the hire date, so the cliff has
+		// passed and the first month after it vests the day after hire
+		{"retroactive", date(2024, 1, 1), date(2023, 1, 1), date(2024, 1, 2), 100, date(2024, 2, 2), date(2024, 1, 1)},
+		// A refresh grant vests from its own commencement date
+		{"refresh", date(2020, 1, 1), date(2025, 1, 1), date(2025, 6, 1), 0, date(2026, 1, 1), date(2026, 1, 1)},
+		{"hire_date", date(2024, 1, 1), time.Time{}, date(2025, 3, 1), 200, date(2025, 4, 1), date(2025, 1, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			employee := Employee{ID: "emp001", StartDate: tt.hireDate, VestingCommencementDate: tt.commencementDate,
+				TotalUnits: 3600, Schedule: schedule}
+
+			result, err := service.calculateVesting(employee, tt.asOfDate)
+			if err != nil {
+				t.Fatalf("calculateVesting failed: %v", err)
+			}
+			if result.VestedUnits != tt.vestedUnits || !result.NextVestDate.Equal(tt.nextVestDate) {
+				t.Errorf("Expected %d units vesting next on %s, got %d on %s", tt.vestedUnits,
+					tt.nextVestDate.Format("2006-01-02"), result.VestedUnits, result.NextVestDate.Format("2006-01-02"))
+			}
+
+			// Results report the hire date alongside the commencement date
+			expectedCommencement := tt.commencementDate
+			if expectedCommencement.IsZero() {
+				expectedCommencement = tt.hireDate
+			}
+			if !result.HireDate.Equal(tt.hireDate) || !result.CommencementDate.Equal(expectedCommencement) {
+				t.Errorf("Expected hire date %s and commencement %s, got %s and %s", tt.hireDate.Format("2006-01-02"),
+					expectedCommencement.Format("2006-01-02"), result.HireDate.Format("2006-01-02"), result.CommencementDate.Format("2006-01-02"))
+			}
+
+			events := vestEvents(employee)
+			if len(events) == 0 || !events[0].Date.Equal(tt.firstVest) {
+				t.Fatalf("Expected the first vest on %s, got %+v", tt.firstVest.Format("2006-01-02"), events)
+			}
+			points, err := service.Timeline(employee, tt.asOfDate, tt.asOfDate, DailyStep)
+			if err != nil {
+				t.Fatalf("Timeline failed: %v", err)
+			}
+			if points[0].VestedUnits != result.VestedUnits {
+				t.Errorf("Timeline has %d vested units, calculateVesting %d", points[0].VestedUnits, result.VestedUnits)
+			}
+		})
+	}
+}
+
+func TestGrantDate(t *testing.T) {
+	service := NewVestingService()
+	employee := Employee{
+		ID:                "emp001",
+		StartDate:         date(2024, 1, 1),
+		GrantDate:         date(2024, 3, 1),
+		BoardApprovalDate: date(2024, 2, 20),
+		TotalUnits:        3600,
+		Schedule:          VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"},
+		StrikePrice:       1,
+	}
+
+	// The contractual term runs from the grant date, while vesting still
+	// runs from the hire date
+	result, explanation, err := service.ExplainVesting(employee, date(2025, 3, 1))
+	if err != nil {
+		t.Fatalf("ExplainVesting failed: %v", err)
+	}
+	if !result.ExpirationDate.Equal(date(2034, 3, 1)) {
+		t.Errorf("Expected expiration on 2034-03-01, got %s", result.ExpirationDate.Format("2006-01-02"))
+	}
+	if result.VestedUnits != 200 {
+		t.Errorf("Expected 200 vested units, got %d", result.VestedUnits)
+	}
+	if history := TermsHistory(employee); !history[0].EffectiveDate.Equal(date(2024, 3, 1)) {
+		t.Errorf("Expected the original terms from the grant date, got %s", history[0].EffectiveDate.Format("2006-01-02"))
+	}
+	if !explanation.CommencementDate.IsZero() || !strings.Contains(explanation.String(), "since 2024-01-01") {
+		t.Errorf("Expected months counted from the hire date, got %s", explanation)
+	}
+
+	retroactive := employee
+	retroactive.VestingCommencementDate = date(2023, 1, 1)
+	if _, explanation, _ := service.ExplainVesting(retroactive, date(2025, 3, 1)); !strings.Contains(explanation.String(), "since 2023-01-01") {
+		t.Errorf("Expected months counted from the commencement date, got %s", explanation)
+	}
+
+	early := employee
+	early.GrantDate = date(2024, 2, 1)
+	if _, err := service.calculateVesting(early, date(2025, 3, 1)); err == nil {
+		t.Error("Expected a grant before board approval to be rejected")
+	}
+}
+
+func TestLifecycleCommencementDate(t *testing.T) {
+	service := NewVestingService()
+	store := NewMemoryEventStore()
+
+	for _, event := range []LifecycleEvent{
+		{Type: EventHired, EmployeeID: "emp001", EffectiveDate: date(2024, 1, 1)},
+		{Type: EventGrantIssued, EmployeeID: "emp001", Units: 3600, EffectiveDate: date(2024, 2, 1),
+			VestingCommencementDate: date(2023, 1, 1), BoardApprovalDate: date(2024, 1, 20),
+			Schedule: VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"}},
+	} {
+		if _, err := service.RecordEvent(store, event); err != nil {
+			t.Fatalf("RecordEvent failed: %v", err)
+		}
+	}
+
+	// 14 months from the commencement date
+	state, err := service.ReplayEmployee(store, "", "emp001", date(2024, 3, 1))
+	if err != nil {
+		t.Fatalf("ReplayEmployee failed: %v", err)
+	}
+	if state.Result.VestedUnits != 200 || !state.Result.HireDate.Equal(date(2024, 1, 1)) {
+		t.Errorf("Expected 200 units for the employee hired on 2024-01-01, got %d for %s",
+			state.Result.VestedUnits, state.Result.HireDate.Format("2006-01-02"))
+	}
+	if !state.Employee.GrantDate.Equal(date(2024, 2, 1)) {
+		t.Errorf("Expected the grant date from the event, got %s", state.Employee.GrantDate.Format("2006-01-02"))
+	}
+
+	if _, err := service.RecordEvent(store, LifecycleEvent{Type: EventHired, EmployeeID: "emp002", EffectiveDate: date(2024, 1, 1)}); err != nil {
+		t.Fatalf("RecordEvent failed: %v", err)
+	}
+	if _, err := service.RecordEvent(store, LifecycleEvent{Type: EventGrantIssued, EmployeeID: "emp002", Units: 100,
+		Schedule:      VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"},
+		EffectiveDate: date(2024, 2, 1), BoardApprovalDate: date(2024, 3, 1)}); err == nil || !strings.Contains(err.Error(), "board approval") {
+		t.Error("Expected a grant before board approval to be rejected")
+	}
+}
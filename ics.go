@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// icsLineLimit is the RFC 5545 maximum line length in octets, excluding CRLF
+const icsLineLimit = 75
+
+// ExportICS writes an RFC 5545 iCalendar file with one all-day event for each
+// of the employee's vest events dated on or after asOfDate
+func (vs *VestingService) ExportICS(w io.Writer, employee Employee, asOfDate time.Time) error {
+	return vs.writeICS(w, vs.applyTenantSettings(employee), asOfDate, time.Now().UTC())
+}
+
+// writeICS does the work of ExportICS with a fixed timestamp. Each event's
+// UID is derived from the tenant, the employee ID and the position of the
+// vest in the grant, so re-importing after a schedule change moves the
+// existing entries instead of adding new ones. SEQUENCE grows with the
+// timestamp so clients accept the later export as an update. Units are
+// restated after any split.
+//
+// The vests a termination drops are exported as cancelled so clients remove
+// them. A calendar only holds the UIDs it was last given, so the vests other
+// schedule changes drop, such as an amendment that shortens the schedule, are
+// not cancelled and stay on the calendar until removed by hand.
+func (vs *VestingService) writeICS(w io.Writer, employee Employee, asOfDate, stamp time.Time) error {
+	if employee.TotalUnits <= 0 {
+		return fmt.Errorf("invalid total units: %d", employee.TotalUnits)
+	}
+
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Vesting Calculator//Vest Schedule//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:" + escapeICSText("Vesting schedule "+employee.ID),
+	}
+
+	dtstamp := stamp.UTC().Format("20060102T150405Z")
+	sequence := stamp.Unix() / 60
+
+	events := vs.splitEvents(employee, vestEvents(employee))
+	for i, event := range events {
+		if event.Date.Before(asOfDate) {
+			continue
+		}
+
+		// A split's event is dated the day before the split
+		basisDate := event.Date
+		if event.Split {
+			basisDate = basisDate.AddDate(0, 0, 1)
+		}
+		totalUnits := vs.splitBasis(employee, basisDate, time.Time{}).Units(grantUnitsAfter(employee, event.Date))
+
+		summary := fmt.Sprintf("Vest: %d units", event.Units)
+		if event.Split {
+			summary = fmt.Sprintf("Stock split: %+d vested units", event.Units)
+		} else if event.Exchange {
+			summary = fmt.Sprintf("Grant exchange: %+d vested units", event.Units)
+		} else if event.Cliff {
+			summary = fmt.Sprintf("Cliff vest: %d units", event.Units)
+		} else if event.Final {
+			summary = fmt.Sprintf("Final vest: %d units", event.Units)
+		}
+		description := fmt.Sprintf("%d units vest for %s.\nCumulative vested: %d of %d units.",
+			event.Units, employee.ID, event.CumulativeUnits, totalUnits)
+
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+icsUID(employee, i+1),
+			"DTSTAMP:"+dtstamp,
+			fmt.Sprintf("SEQUENCE:%d", sequence),
+			"DTSTART;VALUE=DATE:"+event.Date.Format("20060102"),
+			"DTEND;VALUE=DATE:"+event.Date.AddDate(0, 0, 1).Format("20060102"),
+			"SUMMARY:"+escapeICSText(summary),
+			"DESCRIPTION:"+escapeICSText(description),
+			"TRANSP:TRANSPARENT",
+			"END:VEVENT",
+		)
+	}
+
+	if !employee.TerminationDate.IsZero() {
+		unterminated := employee
+		unterminated.TerminationDate = time.Time{}
+		dropped := vs.splitEvents(unterminated, vestEvents(unterminated))
+		for i := len(events); i < len(dropped); i++ {
+			event := dropped[i]
+			if event.Date.Before(asOfDate) {
+				continue
+			}
+			lines = append(lines,
+				"BEGIN:VEVENT",
+				"UID:"+icsUID(employee, i+1),
+				"DTSTAMP:"+dtstamp,
+				fmt.Sprintf("SEQUENCE:%d", sequence),
+				"DTSTART;VALUE=DATE:"+event.Date.Format("20060102"),
+				"DTEND;VALUE=DATE:"+event.Date.AddDate(0, 0, 1).Format("20060102"),
+				"SUMMARY:"+escapeICSText("Cancelled vest"),
+				"STATUS:CANCELLED",
+				"TRANSP:TRANSPARENT",
+				"END:VEVENT",
+			)
+		}
+	}
+
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, foldICSLine(line)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// icsUID returns the UID of the event at a position in the employee's vests,
+// scoped to the employee's tenant when it has one
+func icsUID(employee Employee, position int) string {
+	domain := "vesting-calculator"
+	if employee.TenantID != "" {
+		domain = employee.TenantID + "." + domain
+	}
+	return fmt.Sprintf("vest-%d-%s@%s", position, escapeICSText(employee.ID), escapeICSText(domain))
+}
+
+// escapeICSText escapes a TEXT property value
+func escapeICSText(text string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(text)
+}
+
+// foldICSLine splits a content line into CRLF-terminated lines of at most
+// icsLineLimit octets, continuing each with a leading space and never
+// splitting a UTF-8 sequence
+func foldICSLine(line string) string {
+	var b strings.Builder
+	limit := icsLineLimit
+
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+
+		// Continuation lines spend one octet on the leading space
+		limit = icsLineLimit - 1
+	}
+
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteICS(t *testing.T) {
+	employee := Employee{
+		ID:         "ics;emp",
+		StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		TotalUnits: 36000,
+		Schedule:   VestingSchedule{CliffMonths: 12, VestingMonths: 36, VestingType: "linear"},
+	}
+	asOfDate := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	stamp := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	if err := NewVestingService().writeICS(&buf, employee, asOfDate, stamp); err != nil {
+		t.Fatalf("writeICS failed: %v", err)
+	}
+	output := buf.String()
+
+	if !strings.HasPrefix(output, "BEGIN:VCALENDAR\r\n") || !strings.HasSuffix(output, "END:VCALENDAR\r\n") {
+		t.Error("Calendar is not wrapped in VCALENDAR with CRLF line endings")
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(output, "\r\n"), "\r\n") {
+		if len(line) > icsLineLimit {
+			t.Errorf("Line exceeds %d octets: %q", icsLineLimit, line)
+		}
+		if strings.Contains(line, "\n") {
+			t.Errorf("Line contains a bare newline: %q", line)
+		}
+	}
+
+	// Unfold before inspecting properties
+	unfolded := strings.ReplaceAll(output, "\r\n ", "")
+
+	if count := strings.Count(unfolded, "BEGIN:VEVENT"); count != 24 {
+		t.Errorf("Expected 24 events, got %d", count)
+	}
+	if !strings.Contains(unfolded, "SUMMARY:Cliff vest: 1500 units\r\nDESCRIPTION:1500 units vest for ics\\;emp.\\nCumulative vested: 1500 of 36000 units.") {
+		t.Error("Missing escaped cliff event")
+	}
+	if !strings.Contains(unfolded, "DTSTART;VALUE=DATE:20231201\r\nDTEND;VALUE=DATE:20231202\r\nSUMMARY:Final vest: 1500 units") {
+		t.Error("Missing final vest event")
+	}
+	if !strings.Contains(unfolded, "DTSTAMP:20210601T120000Z") {
+		t.Error("Missing DTSTAMP")
+	}
+}
+
+func TestWriteICSStableUIDs(t *testing.T) {
+	employee := Employee{
+		ID:         "ics_uid",
+		StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		TotalUnits: 36000,
+		Schedule:   VestingSchedule{CliffMonths: 12, VestingMonths: 36, VestingType: "linear"},
+	}
+	asOfDate := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	uidsOf := func(employee Employee, stamp time.Time) ([]string, string) {
+		var buf bytes.Buffer
+		if err := NewVestingService().writeICS(&buf, employee, asOfDate, stamp); err != nil {
+			t.Fatalf("writeICS failed: %v", err)
+		}
+		output := buf.String()
+		return regexp.MustCompile(`UID:(\S+)`).FindAllString(output, -1),
+			regexp.MustCompile(`SEQUENCE:(\d+)`).FindString(output)
+	}
+
+	before, firstSequence := uidsOf(employee, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	// Extending the schedule moves the existing vests and adds new ones
+	employee.Schedule.VestingMonths = 48
+	after, secondSequence := uidsOf(employee, time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC))
+
+	if len(after) <= len(before) {
+		t.Fatalf("Expected more events after extending the schedule, got %d then %d", len(before), len(after))
+	}
+	for i, uid := range before {
+		if after[i] != uid {
+			t.Errorf("Event %d changed UID from %s to %s", i, uid, after[i])
+		}
+	}
+	if firstSequence >= secondSequence {
+		t.Errorf("Expected SEQUENCE to increase, got %s then %s", firstSequence, secondSequence)
+	}
+}
+
+func TestWriteICSTenantAndTermination(t *testing.T) {
+	employee := Employee{
+		ID:         "ics_uid",
+		StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		TotalUnits: 36000,
+		Schedule:   VestingSchedule{CliffMonths: 12, VestingMonths: 36, VestingType: "linear"},
+	}
+	asOfDate := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	stamp := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	export := func(employee Employee) string {
+		var buf bytes.Buffer
+		if err := NewVestingService().writeICS(&buf, employee, asOfDate, stamp); err != nil {
+			t.Fatalf("writeICS failed: %v", err)
+		}
+		return strings.ReplaceAll(buf.String(), "\r\n ", "")
+	}
+
+	// The same employee ID in two tenants never shares a UID
+	acme, globex := employee, employee
+	acme.TenantID, globex.TenantID = "acme", "globex"
+	if !strings.Contains(export(acme), "UID:vest-1-ics_uid@acme.vesting-calculator\r\n") {
+		t.Error("Expected the UID to carry the tenant")
+	}
+	if !strings.Contains(export(globex), "UID:vest-1-ics_uid@globex.vesting-calculator\r\n") {
+		t.Error("Expected the UID to carry the tenant")
+	}
+	if !strings.Contains(export(employee), "UID:vest-1-ics_uid@vesting-calculator\r\n") {
+		t.Error("Expected the default tenant's UID to be unchanged")
+	}
+
+	// Terminating after the first 6 of 24 vests cancels the other 18
+	employee.TerminationDate = time.Date(2022, 6, 15, 0, 0, 0, 0, time.UTC)
+	output := export(employee)
+	if count := strings.Count(output, "BEGIN:VEVENT"); count != 24 {
+		t.Errorf("Expected 24 events, got %d", count)
+	}
+	if count := strings.Count(output, "STATUS:CANCELLED"); count != 18 {
+		t.Errorf("Expected 18 cancelled events, got %d", count)
+	}
+	if !strings.Contains(output, "UID:vest-7-ics_uid@vesting-calculator\r\nDTSTAMP:20210101T000000Z\r\nSEQUENCE:26824320\r\n"+
+		"DTSTART;VALUE=DATE:20220701\r\nDTEND;VALUE=DATE:20220702\r\nSUMMARY:Cancelled vest\r\nSTATUS:CANCELLED") {
+		t.Error("Expected the first dropped vest to be cancelled")
+	}
+}
+
+func TestFoldICSLine(t *testing.T) {
+	line := "DESCRIPTION:" + strings.Repeat("é", 60)
+	folded := foldICSLine(line)
+
+	for _, part := range strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n") {
+		if len(part) > icsLineLimit {
+			t.Errorf("Folded line exceeds %d octets: %q", icsLineLimit, part)
+		}
+	}
+	if strings.ReplaceAll(strings.TrimSuffix(folded, "\r\n"), "\r\n ", "") != line {
+		t.Error("Unfolding does not restore the original line")
+	}
+	if !strings.Contains(folded, "\r\n ") {
+		t.Error("Expected a long line to be folded")
+	}
+}
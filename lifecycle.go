@@ -0,0 +1,466 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Lifecycle event types
+const (
+	EventHired           = "hired"
+	EventGrantIssued     = "grant_issued"
+	EventScheduleAmended = "schedule_amended"
+	EventGrantRepriced   = "grant_repriced"
+	EventGrantExchanged  = "grant_exchanged"
+	EventLeaveStarted    = "leave_started"
+	EventLeaveEnded      = "leave_ended"
+	EventTerminated      = "terminated"
+	EventExercised       = "exercised"
+)
+
+// LifecycleEvent is a fact about an employee's equity. Events are never
+// changed once recorded; a correction is a new event.
+type LifecycleEvent struct {
+	// Sequence is assigned by the event store in recording order
+	Sequence   int    `json:"sequence"`
+	Type       string `json:"type"`
+	TenantID   string `json:"tenant_id,omitempty"`
+	EmployeeID string `json:"employee_id"`
+
+	// EffectiveDate is when the event took effect, and RecordedAt when it
+	// was recorded, by the store's clock. A back-dated event has a
+	// RecordedAt after its EffectiveDate.
+	EffectiveDate time.Time `json:"effective_date"`
+	RecordedAt    time.Time `json:"recorded_at"`
+
+	// Name is set on hired events
+	Name string `json:"name,omitempty"`
+
+	// Units is the grant size on grant_issued events and the units
+	// exercised on exercised events
+	Units int `json:"units,omitempty"`
+
+	// Schedule is set on grant_issued and schedule_amended events
+	Schedule VestingSchedule `json:"schedule,omitzero"`
+
+	// StrikePrice is set on grant_issued and grant_repriced events
+	StrikePrice float64 `json:"strike_price,omitempty"`
+
+	// VestingCommencementDate and BoardApprovalDate may be set on
+	// grant_issued events, whose EffectiveDate is the grant date. The grant
+	// vests from the hire date without a commencement date.
+	VestingCommencementDate time.Time `json:"vesting_commencement_date,omitzero"`
+	BoardApprovalDate       time.Time `json:"board_approval_date,omitzero"`
+
+	// ContractualTermMonths and ExerciseWindowDays may be set on
+	// grant_issued events to override the default option terms
+	ContractualTermMonths int `json:"contractual_term_months,omitempty"`
+	ExerciseWindowDays    int `json:"exercise_window_days,omitempty"`
+
+	// Exchange is set on grant_exchanged events. Its EffectiveDate is
+	// taken from the event.
+	Exchange *GrantExchange `json:"exchange,omitempty"`
+}
+
+// EventStore is an append-only store of lifecycle events
+type EventStore interface {
+	// Append records an event, assigning its Sequence and its RecordedAt
+	// from the store's clock. When check is not nil the event is only
+	// recorded if check accepts the employee's events recorded so far, with
+	// no other event recorded in between.
+	Append(event LifecycleEvent, check func(events []LifecycleEvent) error) (LifecycleEvent, error)
+
+	// Events returns an employee's events in recording order
+	Events(tenantID, employeeID string) ([]LifecycleEvent, error)
+}
+
+// MemoryEventStore keeps lifecycle events in memory
+type MemoryEventStore struct {
+	mu     sync.Mutex
+	events []LifecycleEvent
+	now    func() time.Time
+}
+
+func NewMemoryEventStore() *MemoryEventStore {
+	return &MemoryEventStore{now: time.Now}
+}
+
+// Append records an event once check accepts the employee's events
+func (s *MemoryEventStore) Append(event LifecycleEvent, check func(events []LifecycleEvent) error) (LifecycleEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if check != nil {
+		if err := check(employeeEvents(s.events, event.TenantID, event.EmployeeID)); err != nil {
+			return LifecycleEvent{}, err
+		}
+	}
+
+	event.Sequence = len(s.events) + 1
+	event.RecordedAt = s.now().UTC()
+	s.events = append(s.events, event)
+	return event, nil
+}
+
+// Events returns an employee's events in recording order
+func (s *MemoryEventStore) Events(tenantID, employeeID string) ([]LifecycleEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return employeeEvents(s.events, tenantID, employeeID), nil
+}
+
+// employeeEvents returns the events of one employee, keeping their order
+func employeeEvents(events []LifecycleEvent, tenantID, employeeID string) []LifecycleEvent {
+	var matched []LifecycleEvent
+	for _, event := range events {
+		if event.TenantID == tenantID && event.EmployeeID == employeeID {
+			matched = append(matched, event)
+		}
+	}
+	return matched
+}
+
+// FileEventStore appends lifecycle events to a file as JSON lines
+type FileEventStore struct {
+	path string
+	mu   sync.Mutex
+	now  func() time.Time
+}
+
+func NewFileEventStore(path string) *FileEventStore {
+	return &FileEventStore{path: path, now: time.Now}
+}
+
+// Append records an event at the end of the file once check accepts the
+// employee's events
+func (s *FileEventStore) Append(event LifecycleEvent, check func(events []LifecycleEvent) error) (LifecycleEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events, err := s.read()
+	if err != nil {
+		return LifecycleEvent{}, err
+	}
+	if check != nil {
+		if err := check(employeeEvents(events, event.TenantID, event.EmployeeID)); err != nil {
+			return LifecycleEvent{}, err
+		}
+	}
+	event.Sequence = len(events) + 1
+	event.RecordedAt = s.now().UTC()
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return LifecycleEvent{}, err
+	}
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return LifecycleEvent{}, err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return LifecycleEvent{}, err
+	}
+	return event, file.Close()
+}
+
+// Events returns an employee's events in recording order
+func (s *FileEventStore) Events(tenantID, employeeID string) ([]LifecycleEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	return employeeEvents(events, tenantID, employeeID), nil
+}
+
+// read returns every event in the file
+func (s *FileEventStore) read() ([]LifecycleEvent, error) {
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var events []LifecycleEvent
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event LifecycleEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("invalid lifecycle event: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+// EmployeeState is an employee's equity as of a date, derived by replaying
+// the lifecycle events effective on or before it
+type EmployeeState struct {
+	// Employee is the employee built from the events. Its StartDate is the
+	// hire date; leave is accounted for in Result.
+	Employee Employee
+	AsOfDate time.Time
+
+	Hired    bool
+	HasGrant bool
+	OnLeave  bool
+
+	// LeaveDays is the number of days on leave before AsOfDate. Vesting is
+	// suspended while on leave, so the schedule is pushed back by as many
+	// days.
+	LeaveDays int
+
+	ExercisedUnits   int
+	ExercisableUnits int
+
+	// Result and Explanation are only set once a grant has been issued
+	Result      VestingResult
+	Explanation *VestingExplanation
+
+	// Events are the events replayed, in the order they were applied
+	Events []LifecycleEvent
+
+	leaveStart     time.Time
+	completedLeave int
+}
+
+// RecordEvent appends an event to the store after checking that it is a
+// valid transition. The employee's whole history, including the new event,
+// is replayed so a back-dated event that contradicts a later one is
+// rejected. The store runs the check and the append together, so
+// concurrent events cannot both pass against the same history.
+func (vs *VestingService) RecordEvent(store EventStore, event LifecycleEvent) (LifecycleEvent, error) {
+	return store.Append(event, func(events []LifecycleEvent) error {
+		latest := event.EffectiveDate
+		for _, previous := range events {
+			if previous.EffectiveDate.After(latest) {
+				latest = previous.EffectiveDate
+			}
+		}
+		_, err := vs.replay(append(events, event), latest)
+		return err
+	})
+}
+
+// ReplayEmployee derives an employee's state as of a date from the events
+// in the store
+func (vs *VestingService) ReplayEmployee(store EventStore, tenantID, employeeID string, asOfDate time.Time) (EmployeeState, error) {
+	events, err := store.Events(tenantID, employeeID)
+	if err != nil {
+		return EmployeeState{}, err
+	}
+	if len(events) == 0 {
+		return EmployeeState{}, fmt.Errorf("no events for employee %s", employeeID)
+	}
+	return vs.replay(events, asOfDate)
+}
+
+// replay applies the events effective on or before asOfDate in effective
+// date order, breaking ties by recording order
+func (vs *VestingService) replay(events []LifecycleEvent, asOfDate time.Time) (EmployeeState, error) {
+	events = append([]LifecycleEvent(nil), events...)
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].EffectiveDate.Before(events[j].EffectiveDate)
+	})
+
+	state := EmployeeState{AsOfDate: asOfDate}
+	for _, event := range events {
+		if event.EffectiveDate.After(asOfDate) {
+			break
+		}
+		if err := vs.applyEvent(&state, event); err != nil {
+			return EmployeeState{}, fmt.Errorf("%s event on %s: %w", event.Type, event.EffectiveDate.Format("2006-01-02"), err)
+		}
+		state.Events = append(state.Events, event)
+	}
+
+	if !state.Hired {
+		return EmployeeState{}, fmt.Errorf("employee is not hired as of %s", asOfDate.Format("2006-01-02"))
+	}
+	state.LeaveDays = state.leaveDays(asOfDate)
+
+	if state.HasGrant {
+		result, explanation, err := vs.ExplainVesting(state.vestingEmployee(asOfDate), asOfDate)
+		if err != nil {
+			return EmployeeState{}, err
+		}
+		state.Result = result
+		state.Explanation = explanation
+		state.ExercisableUnits = max(result.ExercisableUnits-state.ExercisedUnits, 0)
+	}
+	return state, nil
+}
+
+// applyEvent folds one event into the state, rejecting invalid transitions
+func (vs *VestingService) applyEvent(state *EmployeeState, event LifecycleEvent) error {
+	if event.Type != EventHired && !state.Hired {
+		return fmt.Errorf("employee is not hired")
+	}
+	terminated := !state.Employee.TerminationDate.IsZero()
+
+	switch event.Type {
+	case EventHired:
+		if state.Hired {
+			return fmt.Errorf("employee is already hired")
+		}
+		state.Hired = true
+		state.Employee = Employee{
+			TenantID:  event.TenantID,
+			ID:        event.EmployeeID,
+			Name:      event.Name,
+			StartDate: event.EffectiveDate,
+		}
+
+	case EventGrantIssued:
+		if state.HasGrant {
+			return fmt.Errorf("employee already has a grant")
+		}
+		if event.Units <= 0 {
+			return fmt.Errorf("invalid total units: %d", event.Units)
+		}
+		if err := ValidateSchedule(event.Schedule); err != nil {
+			return err
+		}
+		if err := validateGrantDates(Employee{GrantDate: event.EffectiveDate, BoardApprovalDate: event.BoardApprovalDate}); err != nil {
+			return err
+		}
+		state.HasGrant = true
+		state.Employee.GrantDate = event.EffectiveDate
+		state.Employee.VestingCommencementDate = event.VestingCommencementDate
+		state.Employee.BoardApprovalDate = event.BoardApprovalDate
+		state.Employee.TotalUnits = event.Units
+		state.Employee.Schedule = event.Schedule
+		state.Employee.StrikePrice = event.StrikePrice
+		state.Employee.ContractualTermMonths = event.ContractualTermMonths
+		state.Employee.ExerciseWindowDays = event.ExerciseWindowDays
+
+	case EventScheduleAmended:
+		if !state.HasGrant {
+			return fmt.Errorf("employee has no grant")
+		}
+		if terminated {
+			return fmt.Errorf("employee is terminated")
+		}
+		if err := state.Employee.AmendSchedule(event.EffectiveDate, event.Schedule); err != nil {
+			return err
+		}
+
+	case EventGrantRepriced:
+		if !state.HasGrant {
+			return fmt.Errorf("employee has no grant")
+		}
+		if terminated {
+			return fmt.Errorf("employee is terminated")
+		}
+		if err := state.Employee.Reprice(event.EffectiveDate, event.StrikePrice); err != nil {
+			return err
+		}
+
+	case EventGrantExchanged:
+		if !state.HasGrant {
+			return fmt.Errorf("employee has no grant")
+		}
+		if terminated {
+			return fmt.Errorf("employee is terminated")
+		}
+		if event.Exchange == nil {
+			return fmt.Errorf("exchange event has no exchange terms")
+		}
+		if state.ExercisedUnits > 0 {
+			// The replacement would be issued for units already exercised
+			return fmt.Errorf("cannot exchange a grant with %d units exercised", state.ExercisedUnits)
+		}
+		exchange := *event.Exchange
+		exchange.EffectiveDate = event.EffectiveDate
+		if err := state.Employee.ExchangeGrant(exchange); err != nil {
+			return err
+		}
+
+	case EventLeaveStarted:
+		if state.OnLeave {
+			return fmt.Errorf("employee is already on leave")
+		}
+		if terminated {
+			return fmt.Errorf("employee is terminated")
+		}
+		state.OnLeave = true
+		state.leaveStart = event.EffectiveDate
+
+	case EventLeaveEnded:
+		if !state.OnLeave {
+			return fmt.Errorf("employee is not on leave")
+		}
+		state.OnLeave = false
+		state.completedLeave += daysBetween(state.leaveStart, event.EffectiveDate)
+
+	case EventTerminated:
+		if terminated {
+			return fmt.Errorf("employee is already terminated")
+		}
+		if state.OnLeave {
+			state.OnLeave = false
+			state.completedLeave += daysBetween(state.leaveStart, event.EffectiveDate)
+		}
+		state.Employee.TerminationDate = event.EffectiveDate
+
+	case EventExercised:
+		if !state.HasGrant {
+			return fmt.Errorf("employee has no grant")
+		}
+		if event.Units <= 0 {
+			return fmt.Errorf("invalid exercised units: %d", event.Units)
+		}
+		result, err := vs.calculateVesting(state.vestingEmployee(event.EffectiveDate), event.EffectiveDate)
+		if err != nil {
+			return err
+		}
+		if result.ExpiredUnits > 0 {
+			return fmt.Errorf("cannot exercise after the grant expired on %s", result.ExpirationDate.Format("2006-01-02"))
+		}
+		if available := result.ExercisableUnits - state.ExercisedUnits; event.Units > available {
+			return fmt.Errorf("cannot exercise %d units, only %d vested and unexercised", event.Units, available)
+		}
+		state.ExercisedUnits += event.Units
+
+	default:
+		return fmt.Errorf("unknown event type: %s", event.Type)
+	}
+	return nil
+}
+
+// leaveDays returns the days spent on leave before date
+func (s *EmployeeState) leaveDays(date time.Time) int {
+	days := s.completedLeave
+	if s.OnLeave {
+		days += daysBetween(s.leaveStart, date)
+	}
+	return days
+}
+
+// vestingEmployee returns the employee to calculate vesting for as of date,
+// with the vesting commencement date pushed back by the leave taken before it
+func (s *EmployeeState) vestingEmployee(date time.Time) Employee {
+	employee := s.Employee
+	employee.VestingCommencementDate = commencementDate(employee).AddDate(0, 0, s.leaveDays(date))
+	return employee
+}
+
+// daysBetween returns the number of calendar days from start to end
+func daysBetween(start, end time.Time) int {
+	startDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	endDay := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	return int(endDay.Sub(startDay).Hours() / 24)
+}
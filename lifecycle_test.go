@@ -0,0 +1,269 @@
+package main
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// recordHistory records a hire, grant, month of leave, exercise and
+// termination for emp001
+func recordHistory(t *testing.T, service *VestingService, store EventStore) {
+	t.Helper()
+
+	schedule := VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"}
+	events := []LifecycleEvent{
+		{Type: EventHired, EmployeeID: "emp001", Name: "Alice Johnson", EffectiveDate: date(2021, 1, 10)},
+		{Type: EventGrantIssued, EmployeeID: "emp001", Units: 10000, Schedule: schedule, EffectiveDate: date(2021, 1, 10)},
+		{Type: EventLeaveStarted, EmployeeID: "emp001", EffectiveDate: date(2022, 3, 1)},
+		{Type: EventLeaveEnded, EmployeeID: "emp001", EffectiveDate: date(2022, 3, 31)},
+		{Type: EventExercised, EmployeeID: "emp001", Units: 1000, EffectiveDate: date(2022, 6, 1)},
+		{Type: EventTerminated, EmployeeID: "emp001", EffectiveDate: date(2023, 1, 1)},
+	}
+	for _, event := range events {
+		if _, err := service.RecordEvent(store, event); err != nil {
+			t.Fatalf("RecordEvent %s failed: %v", event.Type, err)
+		}
+	}
+}
+
+func TestReplayEmployee(t *testing.T) {
+	service := NewVestingService()
+	store := NewMemoryEventStore()
+	recordHistory(t, service, store)
+
+	tests := []struct {
+		name             string
+		asOfDate         time.Time
+		hasGrant         bool
+		onLeave          bool
+		leaveDays        int
+		vestedUnits      int
+		exercisedUnits   int
+		exercisableUnits int
+		events           int
+	}{
+		// 13 months from the hire date, 1 month after the cliff
+		{"before_leave", date(2022, 2, 1), true, false, 0, 277, 0, 277, 2},
+		// Vesting is frozen at its value when the leave started, 14 months
+		{"leave_start", date(2022, 3, 1), true, true, 0, 555, 0, 555, 3},
+		{"during_leave", date(2022, 3, 15), true, true, 14, 555, 0, 555, 3},
+		// The 30 days of leave move the start to 2021-02-09: 16 months
+		{"after_exercise", date(2022, 6, 1), true, false, 30, 1111, 1000, 111, 5},
+		// Terminated on 2023-01-01, 23 months from the shifted start
+		{"after_termination", date(2023, 6, 1), true, false, 30, 3055, 1000, 2055, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := service.ReplayEmployee(store, "", "emp001", tt.asOfDate)
+			if err != nil {
+				t.Fatalf("ReplayEmployee failed: %v", err)
+			}
+
+			if state.HasGrant != tt.hasGrant || state.OnLeave != tt.onLeave {
+				t.Errorf("Expected grant %v and leave %v, got %v and %v", tt.hasGrant, tt.onLeave, state.HasGrant, state.OnLeave)
+			}
+			if state.LeaveDays != tt.leaveDays {
+				t.Errorf("Expected %d leave days, got %d", tt.leaveDays, state.LeaveDays)
+			}
+			if state.Result.VestedUnits != tt.vestedUnits {
+				t.Errorf("Expected %d vested units, got %d", tt.vestedUnits, state.Result.VestedUnits)
+			}
+			if state.ExercisedUnits != tt.exercisedUnits || state.ExercisableUnits != tt.exercisableUnits {
+				t.Errorf("Expected %d exercised and %d exercisable, got %d and %d",
+					tt.exercisedUnits, tt.exercisableUnits, state.ExercisedUnits, state.ExercisableUnits)
+			}
+			if len(state.Events) != tt.events {
+				t.Errorf("Expected %d events replayed, got %d", tt.events, len(state.Events))
+			}
+			if state.Explanation == nil || state.Explanation.VestedUnits != state.Result.VestedUnits {
+				t.Errorf("Expected an explanation matching the result, got %+v", state.Explanation)
+			}
+			if !state.Employee.StartDate.Equal(date(2021, 1, 10)) {
+				t.Errorf("Expected the hire date as start date, got %s", state.Employee.StartDate)
+			}
+		})
+	}
+
+	if _, err := service.ReplayEmployee(store, "", "emp001", date(2021, 1, 1)); err == nil {
+		t.Error("Expected error replaying before the hire date")
+	}
+	if _, err := service.ReplayEmployee(store, "", "emp002", date(2023, 1, 1)); err == nil {
+		t.Error("Expected error replaying an unknown employee")
+	}
+	if _, err := service.ReplayEmployee(store, "acme", "emp001", date(2023, 1, 1)); err == nil {
+		t.Error("Expected events of another tenant to be ignored")
+	}
+}
+
+func TestRecordEventRejectsInvalidTransitions(t *testing.T) {
+	service := NewVestingService()
+	store := NewMemoryEventStore()
+
+	if _, err := service.RecordEvent(store, LifecycleEvent{Type: EventGrantIssued, EmployeeID: "emp001", Units: 100,
+		Schedule: VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"}, EffectiveDate: date(2021, 1, 1)}); err == nil {
+		t.Error("Expected a grant before hire to be rejected")
+	}
+
+	recordHistory(t, service, store)
+	before, _ := store.Events("", "emp001")
+
+	tests := []struct {
+		name  string
+		event LifecycleEvent
+	}{
+		{"rehire", LifecycleEvent{Type: EventHired, EffectiveDate: date(2023, 2, 1)}},
+		{"second_grant", LifecycleEvent{Type: EventGrantIssued, Units: 100,
+			Schedule: VestingSchedule{CliffMonths: 0, VestingMonths: 12, VestingType: "linear"}, EffectiveDate: date(2022, 1, 1)}},
+		{"invalid_amendment", LifecycleEvent{Type: EventScheduleAmended,
+			Schedule: VestingSchedule{CliffMonths: 12, VestingMonths: 12, VestingType: "linear"}, EffectiveDate: date(2022, 1, 1)}},
+		{"amend_after_termination", LifecycleEvent{Type: EventScheduleAmended,
+			Schedule: VestingSchedule{CliffMonths: 0, VestingMonths: 12, VestingType: "linear"}, EffectiveDate: date(2023, 2, 1)}},
+		{"end_without_leave", LifecycleEvent{Type: EventLeaveEnded, EffectiveDate: date(2022, 5, 1)}},
+		{"over_exercise", LifecycleEvent{Type: EventExercised, Units: 3000, EffectiveDate: date(2023, 2, 1)}},
+		// Back-dating the termination leaves nothing vested for the exercise
+		{"backdated_termination", LifecycleEvent{Type: EventTerminated, EffectiveDate: date(2022, 1, 1)}},
+		{"unknown_type", LifecycleEvent{Type: "promoted", EffectiveDate: date(2022, 1, 1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.event.EmployeeID = "emp001"
+			if _, err := service.RecordEvent(store, tt.event); err == nil {
+				t.Error("Expected event to be rejected")
+			}
+		})
+	}
+
+	after, _ := store.Events("", "emp001")
+	if len(after) != len(before) {
+		t.Errorf("Rejected events were stored: %d events before, %d after", len(before), len(after))
+	}
+
+	// Exercising the rest of the vested units is allowed
+	if _, err := service.RecordEvent(store, LifecycleEvent{Type: EventExercised, EmployeeID: "emp001", Units: 2055, EffectiveDate: date(2023, 2, 1)}); err != nil {
+		t.Errorf("Expected exercise of remaining units to succeed, got %v", err)
+	}
+}
+
+func TestFileEventStore(t *testing.T) {
+	service := NewVestingService()
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	recordHistory(t, service, NewFileEventStore(path))
+
+	// A new store over the same file sees the same history
+	store := NewFileEventStore(path)
+	events, err := store.Events("", "emp001")
+	if err != nil {
+		t.Fatalf("Events failed: %v", err)
+	}
+	if len(events) != 6 {
+		t.Fatalf("Expected 6 events, got %d", len(events))
+	}
+	for i, event := range events {
+		if event.Sequence != i+1 {
+			t.Errorf("Expected sequence %d, got %d", i+1, event.Sequence)
+		}
+		if event.RecordedAt.IsZero() {
+			t.Errorf("Event %d has no recorded time", event.Sequence)
+		}
+	}
+	if events[1].Schedule.VestingMonths != 48 || events[0].Name != "Alice Johnson" {
+		t.Errorf("Event fields were not preserved: %+v, %+v", events[0], events[1])
+	}
+
+	state, err := service.ReplayEmployee(store, "", "emp001", date(2023, 6, 1))
+	if err != nil {
+		t.Fatalf("ReplayEmployee failed: %v", err)
+	}
+	if state.Result.VestedUnits != 3055 || state.ExercisableUnits != 2055 {
+		t.Errorf("Expected 3055 vested and 2055 exercisable, got %d and %d", state.Result.VestedUnits, state.ExercisableUnits)
+	}
+}
+
+// interleavedStore holds every reader of an employee's events until all
+// of them have read, the interleaving a separate check and append allows
+type interleavedStore struct {
+	*MemoryEventStore
+	readers sync.WaitGroup
+}
+
+func (s *interleavedStore) Events(tenantID, employeeID string) ([]LifecycleEvent, error) {
+	events, err := s.MemoryEventStore.Events(tenantID, employeeID)
+	s.readers.Done()
+	s.readers.Wait()
+	return events, err
+}
+
+func TestRecordEventIsAtomic(t *testing.T) {
+	service := NewVestingService()
+	memory := NewMemoryEventStore()
+	schedule := VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"}
+	for _, event := range []LifecycleEvent{
+		{Type: EventHired, EmployeeID: "emp001", EffectiveDate: date(2021, 1, 1)},
+		{Type: EventGrantIssued, EmployeeID: "emp001", Units: 10000, Schedule: schedule, EffectiveDate: date(2021, 1, 1)},
+	} {
+		if _, err := service.RecordEvent(memory, event); err != nil {
+			t.Fatalf("RecordEvent failed: %v", err)
+		}
+	}
+
+	// 3333 units are vested, so only three of the exercises fit
+	const exercises = 10
+	store := &interleavedStore{MemoryEventStore: memory}
+	store.readers.Add(exercises)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	recorded := 0
+	for range exercises {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.RecordEvent(store, LifecycleEvent{Type: EventExercised, EmployeeID: "emp001",
+				Units: 1000, EffectiveDate: date(2023, 1, 2)})
+			if err == nil {
+				mu.Lock()
+				recorded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if recorded != 3 {
+		t.Errorf("Expected 3 exercises recorded, got %d", recorded)
+	}
+	state, err := service.ReplayEmployee(memory, "", "emp001", date(2023, 1, 2))
+	if err != nil {
+		t.Fatalf("ReplayEmployee failed: %v", err)
+	}
+	if state.ExercisedUnits != 3000 {
+		t.Errorf("Expected 3000 exercised units, got %d", state.ExercisedUnits)
+	}
+}
+
+func TestRecordEventUsesStoreClock(t *testing.T) {
+	service := NewVestingService()
+	store := NewMemoryEventStore()
+	store.now = func() time.Time { return date(2023, 4, 1) }
+
+	// A caller cannot back-date when an event became known
+	event, err := service.RecordEvent(store, LifecycleEvent{Type: EventHired, EmployeeID: "emp001",
+		EffectiveDate: date(2021, 1, 1), RecordedAt: date(2021, 1, 1)})
+	if err != nil {
+		t.Fatalf("RecordEvent failed: %v", err)
+	}
+	if !event.RecordedAt.Equal(date(2023, 4, 1)) {
+		t.Errorf("Expected the event recorded at 2023-04-01, got %s", event.RecordedAt.Format("2006-01-02"))
+	}
+	if _, err := service.ReplayEmployeeAsKnown(store, "", "emp001", date(2023, 1, 1), date(2023, 3, 31)); err == nil {
+		t.Error("Expected no events known before they were recorded")
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"log/slog"
+)
+
+// redacted replaces personal data in log records
+const redacted = "[REDACTED]"
+
+// SetLogger sends the service's structured logs to logger. Pass nil to
+// discard them.
+func (vs *VestingService) SetLogger(logger *slog.Logger) {
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	vs.logger = logger
+}
+
+// SetLogEmployeeNames includes employee names in log records instead of
+// redacting them
+func (vs *VestingService) SetLogEmployeeNames(enabled bool) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	vs.logNames = enabled
+}
+
+// log returns the configured logger
+func (vs *VestingService) log() *slog.Logger {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	return vs.logger
+}
+
+// employeeAttr describes an employee in a log record, redacting the name
+// unless names are enabled
+func (vs *VestingService) employeeAttr(employee Employee) slog.Attr {
+	vs.mu.Lock()
+	logNames := vs.logNames
+	vs.mu.Unlock()
+
+	if !logNames || employee.Name == "" {
+		return slog.Any("employee", employee)
+	}
+	return slog.Group("employee",
+		slog.String("id", employee.ID),
+		slog.String("tenant_id", employee.TenantID),
+		slog.String("name", employee.Name),
+	)
+}
+
+// LogValue keeps employee names out of logs. Only the identifiers are
+// logged, with the name redacted.
+func (e Employee) LogValue() slog.Value {
+	attrs := []slog.Attr{slog.String("id", e.ID)}
+	if e.TenantID != "" {
+		attrs = append(attrs, slog.String("tenant_id", e.TenantID))
+	}
+	if e.Name != "" {
+		attrs = append(attrs, slog.String("name", redacted))
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// logRecords decodes the JSON log lines written to buf
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Invalid log line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func findRecord(records []map[string]any, msg string) map[string]any {
+	for _, record := range records {
+		if record["msg"] == msg {
+			return record
+		}
+	}
+	return nil
+}
+
+func TestServiceLogging(t *testing.T) {
+	var buf bytes.Buffer
+	service := NewVestingService()
+	service.SetLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	schedule := VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"}
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	employees := []Employee{
+		{ID: "emp001", Name: "Alice Johnson", StartDate: start, TotalUnits: 10000, Schedule: schedule},
+		{ID: "emp002", Name: "Bob Smith", StartDate: start, TotalUnits: 0, Schedule: schedule},
+	}
+
+	if err := service.ProcessBatch(employees, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)); err == nil {
+		t.Fatal("Expected ProcessBatch to fail")
+	}
+	service.ClearCache()
+	if err := service.RegisterTenant("acme", TenantSettings{Rounding: "ceiling"}); err == nil {
+		t.Fatal("Expected RegisterTenant to fail")
+	}
+
+	if strings.Contains(buf.String(), "Alice") || strings.Contains(buf.String(), "Bob") {
+		t.Errorf("Employee names were logged:\n%s", buf.String())
+	}
+
+	records := logRecords(t, &buf)
+
+	started := findRecord(records, "batch started")
+	if started == nil || started["employees"] != float64(2) {
+		t.Errorf("Expected batch started record for 2 employees, got %v", started)
+	}
+
+	finished := findRecord(records, "batch finished")
+	if finished == nil || finished["failed"] != float64(1) {
+		t.Errorf("Expected batch finished record with 1 failure, got %v", finished)
+	}
+
+	rejected := findRecord(records, "employee rejected")
+	if rejected == nil {
+		t.Fatal("Expected employee rejected record")
+	}
+	employee, _ := rejected["employee"].(map[string]any)
+	if employee["id"] != "emp002" || employee["name"] != redacted {
+		t.Errorf("Expected redacted emp002 in rejection, got %v", rejected["employee"])
+	}
+	if rejected["error"] != "invalid total units: 0" || rejected["level"] != "WARN" {
+		t.Errorf("Unexpected rejection record: %v", rejected)
+	}
+
+	if cleared := findRecord(records, "cache cleared"); cleared == nil || cleared["results"] != float64(1) {
+		t.Errorf("Expected cache cleared record for 1 result, got %v", cleared)
+	}
+	if tenant := findRecord(records, "tenant settings rejected"); tenant == nil || tenant["tenant_id"] != "acme" {
+		t.Errorf("Expected tenant settings rejected record for acme, got %v", tenant)
+	}
+
+	// Names are only logged when enabled
+	buf.Reset()
+	service.SetLogEmployeeNames(true)
+	service.ProcessBatch(employees[1:], time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	if !strings.Contains(buf.String(), "Bob Smith") {
+		t.Errorf("Expected employee name in logs once enabled:\n%s", buf.String())
+	}
+
+	// A nil logger discards records
+	buf.Reset()
+	service.SetLogger(nil)
+	service.ClearCache()
+	if buf.Len() != 0 {
+		t.Errorf("Expected no log output, got:\n%s", buf.String())
+	}
+}
+
+func TestEmployeeLogValue(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	logger.Info("test", slog.Any("employee", Employee{ID: "emp001", TenantID: "acme", Name: "Alice Johnson"}))
+
+	output := buf.String()
+	if strings.Contains(output, "Alice") {
+		t.Errorf("Employee name was logged: %s", output)
+	}
+	for _, expected := range []string{"employee.id=emp001", "employee.tenant_id=acme", "employee.name=" + redacted} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in %s", expected, output)
+		}
+	}
+}
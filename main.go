@@ -1,54 +1,30 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
+	"os"
 	"time"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			slog.Error("command failed", slog.String("command", os.Args[1]), slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Create a new vesting service
 	service := NewVestingService()
 
-	// Example employees with different vesting schedules
-	employees := []Employee{
-		{
-			ID:         "emp001",
-			Name:       "Alice Johnson",
-			StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
-			TotalUnits: 48000,
-			Schedule: VestingSchedule{
-				CliffMonths:   12,
-				VestingMonths: 48,
-				VestingType:   "linear",
-			},
-		},
-		{
-			ID:         "emp002",
-			Name:       "Bob Smith",
-			StartDate:  time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
-			TotalUnits: 60000,
-			Schedule: VestingSchedule{
-				CliffMonths:   12,
-				VestingMonths: 48,
-				VestingType:   "backloaded",
-			},
-		},
-		{
-			ID:         "emp003",
-			Name:       "Carol Davis",
-			StartDate:  time.Date(2022, 3, 15, 0, 0, 0, 0, time.UTC),
-			TotalUnits: 40000,
-			Schedule: VestingSchedule{
-				CliffMonths:   6,
-				VestingMonths: 36,
-				VestingType:   "linear",
-			},
-		},
-	}
+	employees := sampleEmployees()
 
 	// Calculate vesting as of today
-	asOfDate := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	asOfDate := sampleAsOfDate
 
 	fmt.Println("=== Pulley Vesting Calculator ===")
 	fmt.Printf("Calculating vesting as of: %s\n\n", asOfDate.Format("2006-01-02"))
@@ -56,7 +32,8 @@ func main() {
 	// Process all employees
 	err := service.ProcessBatch(employees, asOfDate)
 	if err != nil {
-		log.Fatalf("Error processing batch: %v", err)
+		slog.Error("processing batch failed", slog.String("error", err.Error()))
+		os.Exit(1)
 	}
 
 	// Display results
@@ -89,11 +66,158 @@ func main() {
 	employeeIDs := []string{"emp001", "emp002"}
 	results, err := service.GetBatchResults(employeeIDs)
 	if err != nil {
-		log.Printf("Error retrieving batch results: %v", err)
+		slog.Error("retrieving batch results failed", slog.String("error", err.Error()))
 	} else {
 		fmt.Printf("Successfully retrieved %d results\n", len(results))
 		for id, result := range results {
 			fmt.Printf("  %s: %d vested units\n", id, result.VestedUnits)
 		}
 	}
-}
\ No newline at end of file
+}
+
+// sampleAsOfDate is the date the example employees are calculated as of
+var sampleAsOfDate = time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// sampleEmployees returns example employees with different vesting schedules
+func sampleEmployees() []Employee {
+	return []Employee{
+		{
+			ID:         "emp001",
+			Name:       "Alice Johnson",
+			StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			TotalUnits: 48000,
+			Schedule: VestingSchedule{
+				CliffMonths:   12,
+				VestingMonths: 48,
+				VestingType:   "linear",
+			},
+		},
+		{
+			ID:         "emp002",
+			Name:       "Bob Smith",
+			StartDate:  time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+			TotalUnits: 60000,
+			Schedule: VestingSchedule{
+				CliffMonths:   12,
+				VestingMonths: 48,
+				VestingType:   "backloaded",
+			},
+		},
+		{
+			ID:         "emp003",
+			Name:       "Carol Davis",
+			StartDate:  time.Date(2022, 3, 15, 0, 0, 0, 0, time.UTC),
+			TotalUnits: 40000,
+			Schedule: VestingSchedule{
+				CliffMonths:   6,
+				VestingMonths: 36,
+				VestingType:   "linear",
+			},
+		},
+	}
+}
+
+// runCommand runs a CLI subcommand against the example employees
+func runCommand(name string, args []string) error {
+	switch name {
+	case "calendar":
+		return runCalendar(args)
+	case "ics":
+		return runICS(args)
+	case "serve":
+		return runServe(args)
+	default:
+		return fmt.Errorf("unknown command: %s", name)
+	}
+}
+
+// runCalendar prints the upcoming vest events across all employees
+func runCalendar(args []string) error {
+	flags := flag.NewFlagSet("calendar", flag.ContinueOnError)
+	asOf := flags.String("as-of", sampleAsOfDate.Format("2006-01-02"), "date to start the calendar from (YYYY-MM-DD)")
+	days := flags.Int("days", 90, "number of days to include")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	asOfDate, err := time.Parse("2006-01-02", *asOf)
+	if err != nil {
+		return fmt.Errorf("invalid as-of date: %w", err)
+	}
+
+	service := NewVestingService()
+	if err := service.ProcessBatch(sampleEmployees(), asOfDate); err != nil {
+		return fmt.Errorf("processing batch: %w", err)
+	}
+
+	calendar, err := service.UpcomingVests(asOfDate, *days)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("=== Upcoming Vesting Calendar ===")
+	fmt.Printf("Next %d days from %s\n\n", *days, asOfDate.Format("2006-01-02"))
+
+	if len(calendar) == 0 {
+		fmt.Println("No vest events in this period")
+		return nil
+	}
+
+	for _, day := range calendar {
+		fmt.Println(day.Date.Format("2006-01-02"))
+		for _, event := range day.Events {
+			label := ""
+			if event.Cliff {
+				label = " [CLIFF]"
+			} else if event.Exchange {
+				label = " [EXCHANGE]"
+			}
+			fmt.Printf("  %s: %d units%s\n", event.EmployeeID, event.Units, label)
+		}
+		fmt.Printf("  Total: %d units\n\n", day.TotalUnits)
+	}
+
+	return nil
+}
+
+// runICS writes an employee's future vest events as an iCalendar file
+func runICS(args []string) error {
+	flags := flag.NewFlagSet("ics", flag.ContinueOnError)
+	asOf := flags.String("as-of", sampleAsOfDate.Format("2006-01-02"), "only include vests on or after this date (YYYY-MM-DD)")
+	employeeID := flags.String("employee", "", "ID of the employee to export")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	asOfDate, err := time.Parse("2006-01-02", *asOf)
+	if err != nil {
+		return fmt.Errorf("invalid as-of date: %w", err)
+	}
+
+	for _, employee := range sampleEmployees() {
+		if employee.ID == *employeeID {
+			return NewVestingService().ExportICS(os.Stdout, employee, asOfDate)
+		}
+	}
+	return fmt.Errorf("employee not found: %s", *employeeID)
+}
+
+// runServe processes the example employees and serves the service's metrics
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := flags.String("addr", ":9090", "address to listen on")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	service := NewVestingService()
+	if err := service.ProcessBatch(sampleEmployees(), sampleAsOfDate); err != nil {
+		return fmt.Errorf("processing batch: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", service.MetricsHandler())
+
+	fmt.Printf("Serving metrics on %s/metrics\n", *addr)
+	return http.ListenAndServe(*addr, mux)
+}
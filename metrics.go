@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Error types counted by vesting_errors_total
+const (
+	errorTypeTenant     = "tenant_mismatch"
+	errorTypeValidation = "validation"
+	errorTypeNotFound   = "not_found"
+)
+
+// Histogram buckets in seconds
+var (
+	batchDurationBuckets       = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10}
+	calculationDurationBuckets = []float64{0.00001, 0.00005, 0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05}
+)
+
+// serviceMetrics holds the counters and histograms of a VestingService
+type serviceMetrics struct {
+	mu                  sync.Mutex
+	batchDuration       *histogram
+	calculationDuration *histogram
+	errors              map[string]uint64
+	cacheHits           uint64
+	cacheMisses         uint64
+
+	// inFlight is the number of calculation goroutines currently running
+	inFlight atomic.Int64
+}
+
+func newServiceMetrics() *serviceMetrics {
+	return &serviceMetrics{
+		batchDuration:       newHistogram(batchDurationBuckets),
+		calculationDuration: newHistogram(calculationDurationBuckets),
+		errors:              make(map[string]uint64),
+	}
+}
+
+func (m *serviceMetrics) observeBatch(elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.batchDuration.observe(elapsed.Seconds())
+}
+
+func (m *serviceMetrics) observeCalculation(elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calculationDuration.observe(elapsed.Seconds())
+}
+
+func (m *serviceMetrics) countError(errorType string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.errors[errorType]++
+}
+
+func (m *serviceMetrics) countLookup(hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if hit {
+		m.cacheHits++
+	} else {
+		m.cacheMisses++
+	}
+}
+
+// histogram is a Prometheus histogram. counts holds one count per bucket
+// and is cumulative only when written out.
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(value float64) {
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+// MetricsHandler serves the service's metrics in the Prometheus text
+// exposition format, for mounting at /metrics
+func (vs *VestingService) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		vs.WriteMetrics(w)
+	})
+}
+
+// WriteMetrics writes the service's metrics in the Prometheus text
+// exposition format
+func (vs *VestingService) WriteMetrics(w io.Writer) error {
+	vs.mu.Lock()
+	cacheSize := len(vs.cache.results)
+	vs.mu.Unlock()
+
+	m := vs.metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := &metricsWriter{w: w}
+
+	p.help("vesting_batch_duration_seconds", "histogram", "Time taken by ProcessBatch.")
+	p.histogram("vesting_batch_duration_seconds", m.batchDuration)
+
+	p.help("vesting_calculation_duration_seconds", "histogram", "Time taken to calculate vesting for one employee.")
+	p.histogram("vesting_calculation_duration_seconds", m.calculationDuration)
+
+	p.help("vesting_errors_total", "counter", "Failed employee calculations and cache reads by type.")
+	errorTypes := make([]string, 0, len(m.errors))
+	for errorType := range m.errors {
+		errorTypes = append(errorTypes, errorType)
+	}
+	sort.Strings(errorTypes)
+	for _, errorType := range errorTypes {
+		p.sample(fmt.Sprintf("vesting_errors_total{type=%q}", errorType), strconv.FormatUint(m.errors[errorType], 10))
+	}
+
+	p.help("vesting_cache_hits_total", "counter", "Cache reads that found a result.")
+	p.sample("vesting_cache_hits_total", strconv.FormatUint(m.cacheHits, 10))
+
+	p.help("vesting_cache_misses_total", "counter", "Cache reads that found no result.")
+	p.sample("vesting_cache_misses_total", strconv.FormatUint(m.cacheMisses, 10))
+
+	p.help("vesting_cache_size", "gauge", "Number of cached results.")
+	p.sample("vesting_cache_size", strconv.Itoa(cacheSize))
+
+	p.help("vesting_inflight_goroutines", "gauge", "Calculation goroutines currently running.")
+	p.sample("vesting_inflight_goroutines", strconv.FormatInt(m.inFlight.Load(), 10))
+
+	return p.err
+}
+
+// metricsWriter writes exposition format lines, keeping the first error
+type metricsWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (p *metricsWriter) help(name, metricType, help string) {
+	p.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func (p *metricsWriter) sample(name, value string) {
+	p.printf("%s %s\n", name, value)
+}
+
+func (p *metricsWriter) histogram(name string, h *histogram) {
+	var cumulative uint64
+	for i, bound := range h.buckets {
+		cumulative += h.counts[i]
+		p.sample(fmt.Sprintf("%s_bucket{le=%q}", name, strconv.FormatFloat(bound, 'g', -1, 64)), strconv.FormatUint(cumulative, 10))
+	}
+	p.sample(name+`_bucket{le="+Inf"}`, strconv.FormatUint(h.count, 10))
+	p.sample(name+"_sum", strconv.FormatFloat(h.sum, 'g', -1, 64))
+	p.sample(name+"_count", strconv.FormatUint(h.count, 10))
+}
+
+func (p *metricsWriter) printf(format string, args ...any) {
+	if p.err != nil {
+		return
+	}
+	_, p.err = fmt.Fprintf(p.w, format, args...)
+}
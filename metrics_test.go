@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	service := NewVestingService()
+
+	schedule := VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"}
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	asOfDate := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	employees := []Employee{
+		{ID: "emp001", StartDate: start, TotalUnits: 10000, Schedule: schedule},
+		{ID: "emp002", StartDate: start, TotalUnits: 20000, Schedule: schedule},
+	}
+	if err := service.ProcessBatch(employees, asOfDate); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+
+	invalid := []Employee{
+		{ID: "emp003", StartDate: start, TotalUnits: 0, Schedule: schedule},
+		{ID: "emp004", TenantID: "other", StartDate: start, TotalUnits: 10000, Schedule: schedule},
+	}
+	if err := service.ProcessBatch(invalid, asOfDate); err == nil {
+		t.Fatal("Expected ProcessBatch to fail")
+	}
+
+	service.GetResult("emp001")
+	service.GetResult("missing")
+	if _, err := service.GetBatchResults([]string{"emp002", "missing"}); err == nil {
+		t.Fatal("Expected GetBatchResults to fail")
+	}
+
+	recorder := httptest.NewRecorder()
+	service.MetricsHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type: %s", contentType)
+	}
+
+	body := recorder.Body.String()
+	expected := []string{
+		"# TYPE vesting_batch_duration_seconds histogram",
+		`vesting_batch_duration_seconds_bucket{le="+Inf"} 2`,
+		"vesting_batch_duration_seconds_count 2",
+		// The tenant mismatch fails before a calculation is timed
+		`vesting_calculation_duration_seconds_bucket{le="+Inf"} 3`,
+		"vesting_calculation_duration_seconds_count 3",
+		"# TYPE vesting_errors_total counter",
+		`vesting_errors_total{type="not_found"} 1`,
+		`vesting_errors_total{type="tenant_mismatch"} 1`,
+		`vesting_errors_total{type="validation"} 1`,
+		// emp001 and emp002 hit, missing is read twice
+		"vesting_cache_hits_total 2",
+		"vesting_cache_misses_total 2",
+		"vesting_cache_size 2",
+		"vesting_inflight_goroutines 0",
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", line, body)
+		}
+	}
+
+	// Bucket counts are cumulative
+	var previous int
+	for _, line := range strings.Split(body, "\n") {
+		if !strings.HasPrefix(line, "vesting_calculation_duration_seconds_bucket") {
+			continue
+		}
+		count, err := strconv.Atoi(line[strings.LastIndex(line, " ")+1:])
+		if err != nil {
+			t.Fatalf("Invalid bucket line %q: %v", line, err)
+		}
+		if count < previous {
+			t.Errorf("Bucket counts decrease at %q", line)
+		}
+		previous = count
+	}
+
+	recorder = httptest.NewRecorder()
+	service.MetricsHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for POST, got %d", recorder.Code)
+	}
+}
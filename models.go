@@ -5,33 +5,170 @@ import (
 )
 
 type Employee struct {
-	ID         string
-	Name       string
-	StartDate  time.Time
+	// TenantID is the company the employee belongs to. Employees of
+	// different tenants never share cached results.
+	TenantID string
+
+	ID   string
+	Name string
+
+	// StartDate is the hire date. The grant vests from
+	// VestingCommencementDate when it is set, such as a retroactive date for
+	// early employees or a later one for a refresh grant, and from the hire
+	// date otherwise.
+	StartDate               time.Time
+	VestingCommencementDate time.Time
+
+	// GrantDate is when the grant was made, which starts an option's
+	// contractual term, and BoardApprovalDate when the board approved it.
+	// A zero GrantDate is the hire date.
+	GrantDate         time.Time
+	BoardApprovalDate time.Time
+
 	TotalUnits int
 	Schedule   VestingSchedule
+
+	// Amendments change the schedule from their effective dates without
+	// rewriting what vested before them, see AmendSchedule
+	Amendments []ScheduleAmendment
+
+	// TerminationDate stops vesting when set; units that have not vested
+	// by this date never vest
+	TerminationDate time.Time
+
+	// StrikePrice is the exercise price of an option grant, zero for RSUs.
+	// Repricings and an exchange change it from their effective dates, see
+	// Reprice and ExchangeGrant.
+	StrikePrice float64
+	Repricings  []Repricing
+
+	// Exchange, when set, cancels the grant and issues a replacement
+	Exchange *GrantExchange
+
+	// ContractualTermMonths and ExerciseWindowDays override the term of an
+	// option grant and the window to exercise it after termination. Zero
+	// uses the tenant's setting, or 10 years and 90 days without one.
+	ContractualTermMonths int
+	ExerciseWindowDays    int
+
+	// FairValuePerUnit is the grant-date fair value, see ValueGrant
+	FairValuePerUnit float64
+
+	// Calendar holds the market holidays the schedule's business-day
+	// convention moves vest dates off. Nil uses the tenant's calendar, or
+	// weekends alone without one.
+	Calendar *HolidayCalendar
+
+	// VestDates are the company's fixed vest dates for schedules that vest
+	// on them. Nil uses the tenant's vest dates.
+	VestDates []MonthDay
 }
 
 type VestingSchedule struct {
 	CliffMonths   int
 	VestingMonths int
 	VestingType   string // "linear" or "backloaded"
+
+	// Rounding is how fractional units are rounded. "floor" (the default)
+	// rounds the cumulative vested units down and "half-even" rounds them to
+	// the nearest unit, so the final tranche completes the grant.
+	// "tranche-floor" rounds each tranche down on its own and vests the
+	// units lost with the tranche named by Remainder, "last" (the default)
+	// or "first".
+	Rounding  string
+	Remainder string
+
+	// VestDay is "anniversary" to vest on the start date's day of every
+	// month, clamped to the end of shorter months, or "fixed" to accrue
+	// units that way and vest them on the first of the company's vest dates
+	// on or after they accrue. By default each month is added to the
+	// previous vest date, see nextBoundary.
+	VestDay string
+
+	// BusinessDays is the convention that moves vest dates falling on a
+	// weekend or holiday to a business day: "following", "preceding" or
+	// "modified-following". Units vest on the moved date. By default vest
+	// dates are not moved.
+	BusinessDays string
+}
+
+// ScheduleAmendment replaces the terms of a grant from EffectiveDate on
+type ScheduleAmendment struct {
+	EffectiveDate time.Time
+	Schedule      VestingSchedule
+}
+
+// Repricing sets a new strike price for an option grant from EffectiveDate on
+type Repricing struct {
+	EffectiveDate time.Time
+	StrikePrice   float64
+}
+
+// GrantExchange cancels a grant and issues a replacement grant of
+// Numerator new units for every Denominator units cancelled, rounded down.
+// The exchange takes effect at the end of EffectiveDate, like a vest on that
+// date. Units, ratios and prices are on the original grant's basis.
+type GrantExchange struct {
+	EffectiveDate time.Time
+	Numerator     int
+	Denominator   int
+	StrikePrice   float64
+
+	// ResetSchedule, when set, vests the replacement grant afresh from
+	// EffectiveDate, so units vested under the old grant are cancelled with
+	// it. Otherwise the replacement keeps the old grant's vesting and the
+	// units vested under it are converted at the ratio.
+	ResetSchedule *VestingSchedule
 }
 
 type VestingResult struct {
+	TenantID      string
 	EmployeeID    string
 	VestedUnits   int
 	UnvestedUnits int
 	NextVestDate  time.Time
 	AsOfDate      time.Time
+
+	// HireDate is the employee's start date and CommencementDate the date
+	// the grant vests from, which differ for retroactive and refresh grants
+	HireDate         time.Time
+	CommencementDate time.Time
+
+	// StrikePrice and the units are stated on Basis, which differs from the
+	// grant's terms once a stock split has taken effect
+	StrikePrice float64
+	Basis       SplitBasis
+
+	// Rounding and Remainder are the rounding policy the units were
+	// calculated with, see VestingSchedule
+	Rounding  string
+	Remainder string
+
+	// ExercisableUnits are the vested units of an option grant that have
+	// not expired and ExpiredUnits those that have, as of ExpirationDate.
+	// RSUs never expire, so all their vested units are exercisable and
+	// ExpirationDate is zero.
+	ExercisableUnits int
+	ExpiredUnits     int
+	ExpirationDate   time.Time
 }
 
 type VestingCache struct {
-	results map[string]VestingResult
+	results map[cacheKey]VestingResult
+
+	// employees holds the employees last passed to ProcessBatch
+	employees map[cacheKey]Employee
+}
+
+// cacheKey identifies an employee within a tenant
+type cacheKey struct {
+	TenantID   string
+	EmployeeID string
 }
 
 func NewVestingCache() *VestingCache {
 	return &VestingCache{
-		results: make(map[string]VestingResult),
+		results:   make(map[cacheKey]VestingResult),
+		employees: make(map[cacheKey]Employee),
 	}
 }
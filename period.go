@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// EmployeePeriod is one employee's vesting between two dates
+type EmployeePeriod struct {
+	EmployeeID       string
+	StartVestedUnits int
+	EndVestedUnits   int
+
+	// VestedUnits is EndVestedUnits - StartVestedUnits, which always equals
+	// the sum of Events
+	VestedUnits int
+	Events      []VestEvent
+
+	TerminatedInPeriod  bool
+	FullyVestedInPeriod bool
+}
+
+// PeriodReport is the vesting of a batch of employees between two dates
+type PeriodReport struct {
+	From             time.Time
+	To               time.Time
+	Employees        []EmployeePeriod
+	TotalVestedUnits int
+}
+
+// VestedBetween reports the units that vested on or after from and before to
+// for each employee, in input order, along with the company-wide total. The
+// start and end figures are calculateVesting as of from and to. Events are
+// restated after any split, and a split that changes the vested units is an
+// event of its own.
+func (vs *VestingService) VestedBetween(employees []Employee, from, to time.Time) (PeriodReport, error) {
+	if to.Before(from) {
+		return PeriodReport{}, fmt.Errorf("period end %s is before start %s", to.Format("2006-01-02"), from.Format("2006-01-02"))
+	}
+
+	report := PeriodReport{
+		From:      from,
+		To:        to,
+		Employees: make([]EmployeePeriod, 0, len(employees)),
+	}
+
+	for _, employee := range employees {
+		employee = vs.applyTenantSettings(employee)
+		start, err := vs.calculateVesting(employee, from)
+		if err != nil {
+			return PeriodReport{}, fmt.Errorf("employee %s: %w", employee.ID, err)
+		}
+		end, err := vs.calculateVesting(employee, to)
+		if err != nil {
+			return PeriodReport{}, fmt.Errorf("employee %s: %w", employee.ID, err)
+		}
+
+		period := EmployeePeriod{
+			EmployeeID:       employee.ID,
+			StartVestedUnits: start.VestedUnits,
+			EndVestedUnits:   end.VestedUnits,
+			VestedUnits:      end.VestedUnits - start.VestedUnits,
+			Events:           eventsBetween(vs.splitEvents(employee, vestEvents(employee)), from, to),
+			TerminatedInPeriod: !employee.TerminationDate.IsZero() &&
+				!employee.TerminationDate.Before(from) && employee.TerminationDate.Before(to),
+		}
+		for _, event := range period.Events {
+			if event.Final {
+				period.FullyVestedInPeriod = true
+			}
+		}
+
+		report.Employees = append(report.Employees, period)
+		report.TotalVestedUnits += period.VestedUnits
+	}
+
+	return report, nil
+}
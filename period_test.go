@@ -0,0 +1,252 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVestedBetween(t *testing.T) {
+	service := NewVestingService()
+
+	linear := Employee{
+		ID:         "period_linear",
+		Name:       "Linear Employee",
+		StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		TotalUnits: 36000,
+		Schedule: VestingSchedule{
+			CliffMonths:   12,
+			VestingMonths: 36,
+			VestingType:   "linear",
+		},
+	}
+
+	terminated := linear
+	terminated.ID = "period_terminated"
+	terminated.TerminationDate = time.Date(2023, 2, 15, 0, 0, 0, 0, time.UTC)
+
+	finishing := linear
+	finishing.ID = "period_finishing"
+	finishing.StartDate = time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	report, err := service.VestedBetween([]Employee{linear, terminated, finishing}, from, to)
+	if err != nil {
+		t.Fatalf("VestedBetween failed: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		vested      int
+		events      int
+		terminated  bool
+		fullyVested bool
+	}{
+		{name: "period_linear", vested: 4500, events: 3},
+		{name: "period_terminated", vested: 3000, events: 2, terminated: true},
+		{name: "period_finishing", vested: 1500, events: 1, fullyVested: true},
+	}
+
+	if len(report.Employees) != len(tests) {
+		t.Fatalf("Expected %d employees, got %d", len(tests), len(report.Employees))
+	}
+
+	total := 0
+	for i, tt := range tests {
+		period := report.Employees[i]
+		if period.EmployeeID != tt.name {
+			t.Errorf("Expected employee %s at position %d, got %s", tt.name, i, period.EmployeeID)
+		}
+		if period.VestedUnits != tt.vested {
+			t.Errorf("%s: expected %d units vested in period, got %d", tt.name, tt.vested, period.VestedUnits)
+		}
+		if len(period.Events) != tt.events {
+			t.Errorf("%s: expected %d events, got %d", tt.name, tt.events, len(period.Events))
+		}
+		if period.TerminatedInPeriod != tt.terminated {
+			t.Errorf("%s: expected TerminatedInPeriod %v", tt.name, tt.terminated)
+		}
+		if period.FullyVestedInPeriod != tt.fullyVested {
+			t.Errorf("%s: expected FullyVestedInPeriod %v", tt.name, tt.fullyVested)
+		}
+		total += tt.vested
+	}
+
+	if report.TotalVestedUnits != total {
+		t.Errorf("Expected %d total units, got %d", total, report.TotalVestedUnits)
+	}
+
+	if _, err := service.VestedBetween([]Employee{linear}, to, from); err == nil {
+		t.Error("Expected error when the period ends before it starts")
+	}
+}
+
+func TestVestEventsMatchCalculateVesting(t *testing.T) {
+	service := NewVestingService()
+
+	employees := []Employee{
+		{
+			ID:         "events_linear",
+			StartDate:  time.Date(2021, 1, 31, 0, 0, 0, 0, time.UTC),
+			TotalUnits: 10000,
+			Schedule:   VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"},
+		},
+		{
+			ID:         "events_backloaded",
+			StartDate:  time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC),
+			TotalUnits: 60000,
+			Schedule:   VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "backloaded"},
+		},
+		{
+			ID:              "events_terminated",
+			StartDate:       time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC),
+			TotalUnits:      24000,
+			Schedule:        VestingSchedule{CliffMonths: 6, VestingMonths: 24, VestingType: "linear"},
+			TerminationDate: time.Date(2022, 7, 20, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	// Every window sums to the difference of calculateVesting at its ends
+	for _, employee := range employees {
+		events := vestEvents(employee)
+		from := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		for from.Before(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)) {
+			to := from.AddDate(0, 0, 45)
+
+			start, _ := service.calculateVesting(employee, from)
+			end, _ := service.calculateVesting(employee, to)
+
+			sum := 0
+			for _, event := range eventsBetween(events, from, to) {
+				sum += event.Units
+			}
+			if sum != end.VestedUnits-start.VestedUnits {
+				t.Errorf("%s: events between %s and %s sum to %d, calculateVesting differs by %d",
+					employee.ID, from.Format("2006-01-02"), to.Format("2006-01-02"),
+					sum, end.VestedUnits-start.VestedUnits)
+			}
+			from = to
+		}
+
+		if len(events) == 0 || !events[0].Cliff {
+			t.Errorf("%s: expected the first event to be the cliff", employee.ID)
+		}
+	}
+}
+
+func TestVestEventsStopAtGrantTotal(t *testing.T) {
+	service := NewVestingService()
+
+	// Backloaded years run from the cliff, so the fourth year's partial
+	// vesting would carry the grant past its total
+	employee := Employee{
+		ID:         "emp002",
+		StartDate:  time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+		TotalUnits: 60000,
+		Schedule:   VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "backloaded"},
+	}
+
+	events := vestEvents(employee)
+	for i, event := range events {
+		if event.Units <= 0 || event.CumulativeUnits > employee.TotalUnits {
+			t.Errorf("Unexpected event %+v", event)
+		}
+		if event.Final != (i == len(events)-1) {
+			t.Errorf("Expected only the last event to be final, got %+v", event)
+		}
+	}
+	if last := events[len(events)-1]; !last.Date.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) || last.CumulativeUnits != 60000 {
+		t.Errorf("Expected full vesting on 2024-05-01, got %+v", last)
+	}
+
+	for _, asOfDate := range []time.Time{
+		time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		result, err := service.calculateVesting(employee, asOfDate)
+		if err != nil {
+			t.Fatalf("calculateVesting failed: %v", err)
+		}
+		if result.VestedUnits != 60000 || result.UnvestedUnits != 0 {
+			t.Errorf("%s: expected 60000 vested and 0 unvested, got %d and %d",
+				asOfDate.Format("2006-01-02"), result.VestedUnits, result.UnvestedUnits)
+		}
+	}
+
+	report, err := service.VestedBetween([]Employee{employee}, time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("VestedBetween failed: %v", err)
+	}
+	if period := report.Employees[0]; period.VestedUnits != 0 || len(period.Events) != 0 {
+		t.Errorf("Expected nothing to vest after full vesting, got %d units in %d events", period.VestedUnits, len(period.Events))
+	}
+}
+
+func TestVestedBetweenSplit(t *testing.T) {
+	service := NewVestingService()
+	if err := service.RecordSplit(StockSplit{EffectiveDate: time.Date(2023, 2, 15, 0, 0, 0, 0, time.UTC), Numerator: 4, Denominator: 1}); err != nil {
+		t.Fatalf("RecordSplit failed: %v", err)
+	}
+
+	employee := Employee{
+		ID:         "period_split",
+		StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		TotalUnits: 48000,
+		Schedule:   VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"},
+	}
+
+	report, err := service.VestedBetween([]Employee{employee},
+		time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("VestedBetween failed: %v", err)
+	}
+
+	// The 18666 units vested by the split are restated to 74664, and March
+	// brings the restated total to 80000
+	period := report.Employees[0]
+	if period.StartVestedUnits != 16000 || period.EndVestedUnits != 80000 {
+		t.Errorf("Expected 16000 to 80000 vested, got %d to %d", period.StartVestedUnits, period.EndVestedUnits)
+	}
+
+	expected := []VestEvent{
+		{Date: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), Units: 1333, CumulativeUnits: 17333},
+		{Date: time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC), Units: 1333, CumulativeUnits: 18666},
+		{Date: time.Date(2023, 2, 14, 0, 0, 0, 0, time.UTC), Units: 55998, CumulativeUnits: 74664, Split: true},
+		{Date: time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC), Units: 5336, CumulativeUnits: 80000},
+	}
+	if len(period.Events) != len(expected) {
+		t.Fatalf("Expected %d events, got %+v", len(expected), period.Events)
+	}
+	sum := 0
+	for i, event := range period.Events {
+		want := expected[i]
+		if !event.Date.Equal(want.Date) || event.Units != want.Units || event.CumulativeUnits != want.CumulativeUnits || event.Split != want.Split {
+			t.Errorf("Event %d: expected %+v, got %+v", i, want, event)
+		}
+		sum += event.Units
+	}
+	if sum != period.VestedUnits {
+		t.Errorf("Events sum to %d, expected VestedUnits %d", sum, period.VestedUnits)
+	}
+
+	// Every window sums to the difference of calculateVesting at its ends,
+	// including windows that start or end on the split
+	events := service.splitEvents(employee, vestEvents(employee))
+	for _, window := range [][2]time.Time{
+		{time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 2, 15, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2023, 2, 14, 0, 0, 0, 0, time.UTC), time.Date(2023, 2, 15, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2023, 2, 15, 0, 0, 0, 0, time.UTC), time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		start, _ := service.calculateVesting(employee, window[0])
+		end, _ := service.calculateVesting(employee, window[1])
+		sum := 0
+		for _, event := range eventsBetween(events, window[0], window[1]) {
+			sum += event.Units
+		}
+		if sum != end.VestedUnits-start.VestedUnits {
+			t.Errorf("Events between %s and %s sum to %d, calculateVesting differs by %d",
+				window[0].Format("2006-01-02"), window[1].Format("2006-01-02"), sum, end.VestedUnits-start.VestedUnits)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math/big"
+)
+
+// backloadedPercentages are the yearly fractions of a backloaded grant:
+// 10%, 20%, 30% and 40%
+var backloadedPercentages = [backloadedYears]*big.Rat{
+	big.NewRat(1, 10),
+	big.NewRat(2, 10),
+	big.NewRat(3, 10),
+	big.NewRat(4, 10),
+}
+
+// roundUnits rounds an exact number of units to whole units. Floor drops
+// the fraction; half-even rounds to the nearest unit and halves to the even
+// one.
+func roundUnits(units *big.Rat, rounding string) int {
+	quotient, remainder := new(big.Int).QuoRem(units.Num(), units.Denom(), new(big.Int))
+	if remainder.Sign() < 0 {
+		// QuoRem truncates toward zero, floor rounds toward negative infinity
+		quotient.Sub(quotient, big.NewInt(1))
+		remainder.Add(remainder, units.Denom())
+	}
+
+	if rounding == "half-even" {
+		switch new(big.Int).Lsh(remainder, 1).Cmp(units.Denom()) {
+		case 1:
+			quotient.Add(quotient, big.NewInt(1))
+		case 0:
+			if quotient.Bit(0) == 1 {
+				quotient.Add(quotient, big.NewInt(1))
+			}
+		}
+	}
+	return int(quotient.Int64())
+}
+
+// unitsOf returns the exact units of a grant of totalUnits for fraction
+func unitsOf(totalUnits int, fraction *big.Rat) *big.Rat {
+	return new(big.Rat).Mul(new(big.Rat).SetInt64(int64(totalUnits)), fraction)
+}
+
+// ratFloat returns the float64 nearest to r, for display
+func ratFloat(r *big.Rat) float64 {
+	f, _ := r.Float64()
+	return f
+}
+
+// trancheFloorUnits returns the units vested after monthsEmployed months
+// when each tranche, the units a month adds, is rounded down on its own. The
+// units lost to rounding vest with the schedule's first or last tranche, so
+// the tranches add up to the grant exactly.
+func trancheFloorUnits(employee Employee, monthsEmployed int) int {
+	horizon := scheduleHorizon(employee.Schedule)
+	vested, allocated := 0, 0
+	first, last := -1, -1
+	previous := new(big.Rat)
+
+	for months := 0; months <= horizon; months++ {
+		fraction := vestedFraction(employee, months, nil)
+		tranche := new(big.Rat).Sub(fraction, previous)
+		previous = fraction
+		if tranche.Sign() == 0 {
+			continue
+		}
+
+		units := roundUnits(unitsOf(employee.TotalUnits, tranche), "floor")
+		allocated += units
+		if months <= monthsEmployed {
+			vested += units
+		}
+		if first < 0 {
+			first = months
+		}
+		last = months
+	}
+
+	remainderAt := last
+	if employee.Schedule.Remainder == "first" {
+		remainderAt = first
+	}
+	if remainderAt >= 0 && remainderAt <= monthsEmployed {
+		vested += employee.TotalUnits - allocated
+	}
+	return vested
+}
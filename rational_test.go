@@ -0,0 +1,292 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+// TestExactVestingCorpus checks vestedForMonths against values worked out by
+// hand. The first cases came out one unit short with float64 arithmetic.
+func TestExactVestingCorpus(t *testing.T) {
+	tests := []struct {
+		name           string
+		totalUnits     int
+		schedule       VestingSchedule
+		monthsEmployed int
+		vestedUnits    int
+		fraction       string
+		exactUnits     string
+	}{
+		{
+			// 1000 * 19/19 = 1000, float64 gave 999
+			name:           "linear_full_19_months",
+			totalUnits:     1000,
+			schedule:       VestingSchedule{CliffMonths: 12, VestingMonths: 31, VestingType: "linear"},
+			monthsEmployed: 31,
+			vestedUnits:    1000,
+			fraction:       "1",
+			exactUnits:     "1000",
+		},
+		{
+			// 48000 * 21/35 = 28800, float64 gave 28799
+			name:           "linear_three_fifths",
+			totalUnits:     48000,
+			schedule:       VestingSchedule{CliffMonths: 12, VestingMonths: 47, VestingType: "linear"},
+			monthsEmployed: 33,
+			vestedUnits:    28800,
+			fraction:       "3/5",
+			exactUnits:     "28800",
+		},
+		{
+			// 100000 * 11/11 = 100000, float64 gave 99999
+			name:           "linear_no_cliff_complete",
+			totalUnits:     100000,
+			schedule:       VestingSchedule{CliffMonths: 0, VestingMonths: 11, VestingType: "linear"},
+			monthsEmployed: 11,
+			vestedUnits:    100000,
+			fraction:       "1",
+			exactUnits:     "100000",
+		},
+		{
+			// 10000 * 13/36 = 3611 1/9
+			name:           "linear_floor",
+			totalUnits:     10000,
+			schedule:       VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"},
+			monthsEmployed: 25,
+			vestedUnits:    3611,
+			fraction:       "13/36",
+			exactUnits:     "32500/9",
+		},
+		{
+			// 10000 * 2/36 = 555 5/9
+			name:           "linear_half_even_up",
+			totalUnits:     10000,
+			schedule:       VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear", Rounding: "half-even"},
+			monthsEmployed: 14,
+			vestedUnits:    556,
+			fraction:       "1/18",
+			exactUnits:     "5000/9",
+		},
+		{
+			// 9 * 1/2 = 4 1/2, a tie rounded to the even unit
+			name:           "linear_half_even_tie_down",
+			totalUnits:     9,
+			schedule:       VestingSchedule{CliffMonths: 0, VestingMonths: 2, VestingType: "linear", Rounding: "half-even"},
+			monthsEmployed: 1,
+			vestedUnits:    4,
+			fraction:       "1/2",
+			exactUnits:     "9/2",
+		},
+		{
+			// 3 * 1/2 = 1 1/2, a tie rounded to the even unit
+			name:           "linear_half_even_tie_up",
+			totalUnits:     3,
+			schedule:       VestingSchedule{CliffMonths: 0, VestingMonths: 2, VestingType: "linear", Rounding: "half-even"},
+			monthsEmployed: 1,
+			vestedUnits:    2,
+			fraction:       "1/2",
+			exactUnits:     "3/2",
+		},
+		{
+			// 123456789 * 35/36 = 120027433 3/4
+			name:           "linear_large_grant",
+			totalUnits:     123456789,
+			schedule:       VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"},
+			monthsEmployed: 47,
+			vestedUnits:    120027433,
+			fraction:       "35/36",
+			exactUnits:     "480109735/4",
+		},
+		{
+			// 10% + 20% + 9/12 of 20% = 9/20, and 10 * 9/20 = 4 1/2;
+			// float64 rounded the tie up to 5
+			name:           "backloaded_half_even_tie",
+			totalUnits:     10,
+			schedule:       VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "backloaded", Rounding: "half-even"},
+			monthsEmployed: 33,
+			vestedUnits:    4,
+			fraction:       "9/20",
+			exactUnits:     "9/2",
+		},
+		{
+			// 10% + 20% = 3/10, and 15 * 3/10 = 4 1/2; float64 gave 5
+			name:           "backloaded_whole_years_tie",
+			totalUnits:     15,
+			schedule:       VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "backloaded", Rounding: "half-even"},
+			monthsEmployed: 24,
+			vestedUnits:    4,
+			fraction:       "3/10",
+			exactUnits:     "9/2",
+		},
+		{
+			// 10% + 20% + 30% + 40% = 1
+			name:           "backloaded_complete",
+			totalUnits:     60000,
+			schedule:       VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "backloaded"},
+			monthsEmployed: 48,
+			vestedUnits:    60000,
+			fraction:       "1",
+			exactUnits:     "60000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			employee := Employee{ID: "emp001", TotalUnits: tt.totalUnits, Schedule: tt.schedule}
+			explain := &VestingExplanation{}
+
+			if vested := vestedForMonths(employee, tt.monthsEmployed, explain); vested != tt.vestedUnits {
+				t.Errorf("Expected %d vested units, got %d", tt.vestedUnits, vested)
+			}
+			if explain.VestedFraction != tt.fraction || explain.ExactUnits != tt.exactUnits {
+				t.Errorf("Expected fraction %s and exact units %s, got %s and %s",
+					tt.fraction, tt.exactUnits, explain.VestedFraction, explain.ExactUnits)
+			}
+			// The same figures come out without an explanation
+			if vested := vestedForMonths(employee, tt.monthsEmployed, nil); vested != tt.vestedUnits {
+				t.Errorf("Expected %d vested units without an explanation, got %d", tt.vestedUnits, vested)
+			}
+		})
+	}
+}
+
+func TestRoundUnits(t *testing.T) {
+	tests := []struct {
+		units    *big.Rat
+		floor    int
+		halfEven int
+	}{
+		{big.NewRat(5, 2), 2, 2},
+		{big.NewRat(7, 2), 3, 4},
+		{big.NewRat(10, 3), 3, 3},
+		{big.NewRat(11, 3), 3, 4},
+		{big.NewRat(12, 1), 12, 12},
+		{big.NewRat(0, 1), 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := roundUnits(tt.units, "floor"); got != tt.floor {
+			t.Errorf("roundUnits(%s, floor) = %d, want %d", tt.units.RatString(), got, tt.floor)
+		}
+		if got := roundUnits(tt.units, ""); got != tt.floor {
+			t.Errorf("roundUnits(%s, default) = %d, want %d", tt.units.RatString(), got, tt.floor)
+		}
+		if got := roundUnits(tt.units, "half-even"); got != tt.halfEven {
+			t.Errorf("roundUnits(%s, half-even) = %d, want %d", tt.units.RatString(), got, tt.halfEven)
+		}
+	}
+}
+
+func TestRoundingPolicies(t *testing.T) {
+	service := NewVestingService()
+
+	tests := []struct {
+		rounding   string
+		remainder  string
+		tranches   []int
+		resultName string
+	}{
+		// 1000/6 = 166 2/3 units a month
+		{"floor", "", []int{166, 167, 167, 166, 167, 167}, "floor"},
+		{"", "", []int{166, 167, 167, 166, 167, 167}, "floor"},
+		{"half-even", "", []int{167, 166, 167, 167, 166, 167}, "half-even"},
+		{"tranche-floor", "", []int{166, 166, 166, 166, 166, 170}, "tranche-floor"},
+		{"tranche-floor", "last", []int{166, 166, 166, 166, 166, 170}, "tranche-floor"},
+		{"tranche-floor", "first", []int{170, 166, 166, 166, 166, 166}, "tranche-floor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rounding+"_"+tt.remainder, func(t *testing.T) {
+			employee := Employee{
+				ID:         "emp001",
+				StartDate:  date(2024, 1, 1),
+				TotalUnits: 1000,
+				Schedule:   VestingSchedule{CliffMonths: 0, VestingMonths: 6, VestingType: "linear", Rounding: tt.rounding, Remainder: tt.remainder},
+			}
+			if err := ValidateSchedule(employee.Schedule); err != nil {
+				t.Fatalf("ValidateSchedule failed: %v", err)
+			}
+
+			events := vestEvents(employee)
+			if len(events) != len(tt.tranches) {
+				t.Fatalf("Expected %d tranches, got %+v", len(tt.tranches), events)
+			}
+			for i, event := range events {
+				if event.Units != tt.tranches[i] {
+					t.Errorf("Tranche %d: expected %d units, got %d", i+1, tt.tranches[i], event.Units)
+				}
+			}
+
+			// Results agree with the tranches and name the policy
+			result, err := service.calculateVesting(employee, date(2024, 3, 15))
+			if err != nil {
+				t.Fatalf("calculateVesting failed: %v", err)
+			}
+			if expected := events[2].CumulativeUnits; result.VestedUnits != expected {
+				t.Errorf("Expected %d vested units after three tranches, got %d", expected, result.VestedUnits)
+			}
+			expectedRemainder := ""
+			if tt.rounding == "tranche-floor" {
+				expectedRemainder = tt.remainder
+				if expectedRemainder == "" {
+					expectedRemainder = "last"
+				}
+			}
+			if result.Rounding != tt.resultName || result.Remainder != expectedRemainder {
+				t.Errorf("Expected policy %s/%s in result, got %s/%s", tt.resultName, expectedRemainder, result.Rounding, result.Remainder)
+			}
+		})
+	}
+}
+
+func TestRoundingPoliciesReconcile(t *testing.T) {
+	policies := []VestingSchedule{
+		{Rounding: "floor"},
+		{Rounding: "half-even"},
+		{Rounding: "tranche-floor", Remainder: "first"},
+		{Rounding: "tranche-floor", Remainder: "last"},
+	}
+	schedules := []VestingSchedule{
+		{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"},
+		{CliffMonths: 0, VestingMonths: 7, VestingType: "linear"},
+		{CliffMonths: 12, VestingMonths: 48, VestingType: "backloaded"},
+	}
+
+	for _, policy := range policies {
+		for _, schedule := range schedules {
+			for _, totalUnits := range []int{1, 7, 1001, 48000, 123457} {
+				schedule.Rounding, schedule.Remainder = policy.Rounding, policy.Remainder
+				employee := Employee{ID: "emp001", StartDate: date(2024, 1, 1), TotalUnits: totalUnits, Schedule: schedule}
+
+				sum := 0
+				for _, event := range vestEvents(employee) {
+					sum += event.Units
+				}
+				if sum != totalUnits {
+					t.Errorf("%s %s/%s with %d units: tranches sum to %d",
+						schedule.VestingType, policy.Rounding, policy.Remainder, totalUnits, sum)
+				}
+			}
+		}
+	}
+}
+
+func TestValidateRemainder(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule VestingSchedule
+	}{
+		{"remainder_with_floor", VestingSchedule{CliffMonths: 0, VestingMonths: 12, VestingType: "linear", Rounding: "floor", Remainder: "first"}},
+		{"remainder_without_rounding", VestingSchedule{CliffMonths: 0, VestingMonths: 12, VestingType: "linear", Remainder: "last"}},
+		{"unknown_remainder", VestingSchedule{CliffMonths: 0, VestingMonths: 12, VestingType: "linear", Rounding: "tranche-floor", Remainder: "middle"}},
+		{"unknown_rounding", VestingSchedule{CliffMonths: 0, VestingMonths: 12, VestingType: "linear", Rounding: "ceiling"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateSchedule(tt.schedule); err == nil {
+				t.Error("Expected schedule to be rejected")
+			}
+		})
+	}
+}
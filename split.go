@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// StockSplit is a split of the company's stock. A 4:1 split has Numerator 4
+// and Denominator 1, a 1:10 reverse split Numerator 1 and Denominator 10.
+type StockSplit struct {
+	EffectiveDate time.Time
+	Numerator     int
+	Denominator   int
+
+	// RecordedAt is when the split was recorded, always set by RecordSplit
+	// from the service's clock. Reports as known at an earlier time ignore
+	// the split.
+	RecordedAt time.Time
+}
+
+// SplitBasis is the share basis a result is stated in: the cumulative ratio
+// of the splits that took effect after the grant and on or before the as-of
+// date. The grant's own basis is 1:1.
+type SplitBasis struct {
+	Numerator   int
+	Denominator int
+
+	// SplitDate is the effective date of the latest split applied, zero on
+	// the grant's own basis
+	SplitDate time.Time
+}
+
+// grantBasis is the basis of a grant that has not been split
+var grantBasis = SplitBasis{Numerator: 1, Denominator: 1}
+
+// RecordSplit records a stock split of the default tenant. Results as of
+// the split's effective date and later are adjusted for it.
+func (vs *VestingService) RecordSplit(split StockSplit) error {
+	return vs.recordSplit(defaultTenant, split)
+}
+
+// RecordSplit records a stock split of the tenant
+func (ts *TenantService) RecordSplit(split StockSplit) error {
+	return ts.vs.recordSplit(ts.tenantID, split)
+}
+
+func (vs *VestingService) recordSplit(tenantID string, split StockSplit) error {
+	if split.EffectiveDate.IsZero() {
+		return fmt.Errorf("split has no effective date")
+	}
+	if split.Numerator <= 0 || split.Denominator <= 0 || split.Numerator == split.Denominator {
+		return fmt.Errorf("invalid split ratio: %d:%d", split.Numerator, split.Denominator)
+	}
+	split.RecordedAt = vs.now().UTC()
+
+	vs.mu.Lock()
+	for _, existing := range vs.splits[tenantID] {
+		if existing.EffectiveDate.Equal(split.EffectiveDate) {
+			vs.mu.Unlock()
+			return fmt.Errorf("split already recorded on %s", split.EffectiveDate.Format("2006-01-02"))
+		}
+	}
+
+	splits := append(vs.splits[tenantID], split)
+	sort.Slice(splits, func(i, j int) bool {
+		return splits[i].EffectiveDate.Before(splits[j].EffectiveDate)
+	})
+	vs.splits[tenantID] = splits
+	vs.mu.Unlock()
+
+	vs.log().Info("stock split recorded",
+		slog.String("tenant_id", tenantID),
+		slog.Time("effective_date", split.EffectiveDate),
+		slog.String("ratio", fmt.Sprintf("%d:%d", split.Numerator, split.Denominator)))
+	return nil
+}
+
+// splitBasis returns the basis of the employee's grant as of a date, using
+// only the splits recorded by knownAt unless it is zero. Splits on or before
+// the start date are already reflected in the grant's terms.
+func (vs *VestingService) splitBasis(employee Employee, asOfDate, knownAt time.Time) SplitBasis {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	basis := grantBasis
+	for _, split := range vs.splits[employee.TenantID] {
+		if split.EffectiveDate.After(asOfDate) {
+			break
+		}
+		if !split.EffectiveDate.After(grantDate(employee)) {
+			continue
+		}
+		if !knownAt.IsZero() && split.RecordedAt.After(knownAt) {
+			continue
+		}
+		basis.Numerator *= split.Numerator
+		basis.Denominator *= split.Denominator
+		divisor := gcd(basis.Numerator, basis.Denominator)
+		basis.Numerator /= divisor
+		basis.Denominator /= divisor
+		basis.SplitDate = split.EffectiveDate
+	}
+	return basis
+}
+
+// Units converts units on the grant's basis to this basis. Fractional
+// shares are rounded down, as they are paid out in cash.
+func (b SplitBasis) Units(units int) int {
+	return int(int64(units) * int64(b.Numerator) / int64(b.Denominator))
+}
+
+// Price converts a per-share price on the grant's basis to this basis
+func (b SplitBasis) Price(price float64) float64 {
+	return price * float64(b.Denominator) / float64(b.Numerator)
+}
+
+// String returns the ratio as "4:1"
+func (b SplitBasis) String() string {
+	return fmt.Sprintf("%d:%d", b.Numerator, b.Denominator)
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// splitEvents restates the employee's vest events on the basis in force once
+// each one vests and adds an event for every split that changes the vested
+// units. Since events take effect at the end of their date, a split's event
+// is dated the day before the split.
+func (vs *VestingService) splitEvents(employee Employee, events []VestEvent) []VestEvent {
+	vs.mu.Lock()
+	var dates []time.Time
+	for _, split := range vs.splits[employee.TenantID] {
+		if split.EffectiveDate.After(grantDate(employee)) {
+			dates = append(dates, split.EffectiveDate)
+		}
+	}
+	vs.mu.Unlock()
+	if len(dates) == 0 {
+		return events
+	}
+
+	restated := make([]VestEvent, 0, len(events)+len(dates))
+	units, vested := 0, 0
+	addSplits := func(splits []time.Time) {
+		for _, date := range splits {
+			cumulative := vs.splitBasis(employee, date, time.Time{}).Units(units)
+			if cumulative == vested {
+				continue
+			}
+			restated = append(restated, VestEvent{
+				EmployeeID:      employee.ID,
+				Date:            date.AddDate(0, 0, -1),
+				Units:           cumulative - vested,
+				CumulativeUnits: cumulative,
+				Split:           true,
+			})
+			vested = cumulative
+		}
+	}
+
+	for _, event := range events {
+		next := 0
+		for next < len(dates) && !dates[next].After(event.Date) {
+			next++
+		}
+		addSplits(dates[:next])
+		dates = dates[next:]
+
+		units = event.CumulativeUnits
+		event.CumulativeUnits = vs.splitBasis(employee, event.Date, time.Time{}).Units(units)
+		event.Units = event.CumulativeUnits - vested
+		vested = event.CumulativeUnits
+		restated = append(restated, event)
+	}
+	addSplits(dates)
+	return restated
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestStockSplits(t *testing.T) {
+	service := NewVestingService()
+	if err := service.RecordSplit(StockSplit{EffectiveDate: date(2023, 1, 1), Numerator: 4, Denominator: 1}); err != nil {
+		t.Fatalf("RecordSplit failed: %v", err)
+	}
+	if err := service.RecordSplit(StockSplit{EffectiveDate: date(2024, 1, 1), Numerator: 1, Denominator: 3}); err != nil {
+		t.Fatalf("RecordSplit failed: %v", err)
+	}
+
+	schedule := VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"}
+	early := Employee{ID: "emp001", StartDate: date(2021, 1, 1), TotalUnits: 10000, Schedule: schedule, StrikePrice: 2}
+	late := Employee{ID: "emp002", StartDate: date(2023, 6, 1), TotalUnits: 9000, Schedule: schedule, StrikePrice: 1.2}
+
+	tests := []struct {
+		name          string
+		employee      Employee
+		asOfDate      time.Time
+		vestedUnits   int
+		unvestedUnits int
+		strikePrice   float64
+		basis         string
+		splitDate     time.Time
+	}{
+		// 24 months: 3333 units on the grant's basis
+		{"before_split", early, date(2022, 12, 31), 3333, 6667, 2, "1:1", time.Time{}},
+		{"on_split_date", early, date(2023, 1, 1), 13332, 26668, 0.5, "4:1", date(2023, 1, 1)},
+		// 41 months: 8055 units, restated 4:3 and rounded down
+		{"after_reverse_split", early, date(2024, 6, 1), 10740, 2593, 1.5, "4:3", date(2024, 1, 1)},
+		// The 4:1 split was before the grant, so only the reverse split applies
+		{"granted_after_split", late, date(2023, 12, 1), 0, 9000, 1.2, "1:1", time.Time{}},
+		// 15 months: 750 units, restated 1:3
+		{"granted_after_split_reversed", late, date(2024, 9, 1), 250, 2750, 3.6, "1:3", date(2024, 1, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := service.calculateVesting(tt.employee, tt.asOfDate)
+			if err != nil {
+				t.Fatalf("calculateVesting failed: %v", err)
+			}
+			if result.VestedUnits != tt.vestedUnits || result.UnvestedUnits != tt.unvestedUnits {
+				t.Errorf("Expected %d vested and %d unvested, got %d and %d",
+					tt.vestedUnits, tt.unvestedUnits, result.VestedUnits, result.UnvestedUnits)
+			}
+			if math.Abs(result.StrikePrice-tt.strikePrice) > 1e-9 {
+				t.Errorf("Expected strike price %.4f, got %.4f", tt.strikePrice, result.StrikePrice)
+			}
+			if result.Basis.String() != tt.basis || !result.Basis.SplitDate.Equal(tt.splitDate) {
+				t.Errorf("Expected basis %s from %s, got %s from %s", tt.basis, tt.splitDate.Format("2006-01-02"),
+					result.Basis, result.Basis.SplitDate.Format("2006-01-02"))
+			}
+		})
+	}
+
+	// Timeline points are restated the same way
+	points, err := service.Timeline(early, date(2022, 12, 1), date(2024, 6, 1), MonthlyStep)
+	if err != nil {
+		t.Fatalf("Timeline failed: %v", err)
+	}
+	for _, point := range points {
+		expected, _ := service.calculateVesting(early, point.Date)
+		if point.VestedUnits != expected.VestedUnits || point.UnvestedUnits != expected.UnvestedUnits {
+			t.Errorf("Timeline at %s has %d/%d units, calculateVesting %d/%d", point.Date.Format("2006-01-02"),
+				point.VestedUnits, point.UnvestedUnits, expected.VestedUnits, expected.UnvestedUnits)
+		}
+	}
+
+	_, explanation, err := service.ExplainVesting(early, date(2023, 2, 1))
+	if err != nil {
+		t.Fatalf("ExplainVesting failed: %v", err)
+	}
+	if explanation.SplitRatio != "4:1" {
+		t.Errorf("Expected split ratio 4:1 in explanation, got %q", explanation.SplitRatio)
+	}
+
+	// Splits of another tenant do not apply
+	other := early
+	other.TenantID = "acme"
+	result, err := service.calculateVesting(other, date(2023, 6, 1))
+	if err != nil {
+		t.Fatalf("calculateVesting failed: %v", err)
+	}
+	if result.Basis != grantBasis {
+		t.Errorf("Expected another tenant's grant to stay 1:1, got %s", result.Basis)
+	}
+}
+
+func TestRecordSplitValidation(t *testing.T) {
+	service := NewVestingService()
+	if err := service.RecordSplit(StockSplit{EffectiveDate: date(2023, 1, 1), Numerator: 2, Denominator: 1}); err != nil {
+		t.Fatalf("RecordSplit failed: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		split StockSplit
+	}{
+		{"no_date", StockSplit{Numerator: 2, Denominator: 1}},
+		{"even_ratio", StockSplit{EffectiveDate: date(2024, 1, 1), Numerator: 3, Denominator: 3}},
+		{"zero_numerator", StockSplit{EffectiveDate: date(2024, 1, 1), Numerator: 0, Denominator: 1}},
+		{"negative_denominator", StockSplit{EffectiveDate: date(2024, 1, 1), Numerator: 1, Denominator: -2}},
+		{"same_date", StockSplit{EffectiveDate: date(2023, 1, 1), Numerator: 3, Denominator: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := service.RecordSplit(tt.split); err == nil {
+				t.Error("Expected split to be rejected")
+			}
+		})
+	}
+}
+
+func TestWebhookIgnoresSplit(t *testing.T) {
+	service := NewVestingService()
+	if err := service.RecordSplit(StockSplit{EffectiveDate: date(2023, 1, 1), Numerator: 4, Denominator: 1}); err != nil {
+		t.Fatalf("RecordSplit failed: %v", err)
+	}
+
+	employee := Employee{
+		ID:         "emp001",
+		StartDate:  date(2021, 1, 1),
+		TotalUnits: 10000,
+		Schedule:   VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"},
+	}
+	dispatcher := NewWebhookDispatcher(nil)
+
+	// Both as-of dates are in month 24, so only the basis changes
+	before, _ := service.calculateVesting(employee, date(2022, 12, 15))
+	after, _ := service.calculateVesting(employee, date(2023, 1, 1))
+	dispatcher.Observe(employee, before)
+	if events := dispatcher.Observe(employee, after); len(events) != 0 {
+		t.Errorf("Expected no webhook events across a split, got %+v", events)
+	}
+
+	later, _ := service.calculateVesting(employee, date(2023, 1, 2))
+	events := dispatcher.Observe(employee, later)
+	if len(events) != 1 || events[0].Type != WebhookVest || events[0].PreviousVestedUnits != 13332 {
+		t.Errorf("Expected a vest from 13332 units, got %+v", events)
+	}
+}
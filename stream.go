@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"iter"
+	"time"
+)
+
+// StreamBatch calculates vesting for each employee of the default tenant as
+// the sequence is consumed. Every successful result is stored in the cache
+// before it is yielded, so a stream that is fully drained leaves the cache in
+// the same state as ProcessBatch. A failed employee yields its error and the
+// stream moves on to the next employee; stop ranging to abort early.
+func (vs *VestingService) StreamBatch(employees iter.Seq[Employee], asOfDate time.Time) iter.Seq2[VestingResult, error] {
+	return vs.streamBatch(defaultTenant, employees, asOfDate)
+}
+
+func (vs *VestingService) streamBatch(tenantID string, employees iter.Seq[Employee], asOfDate time.Time) iter.Seq2[VestingResult, error] {
+	return func(yield func(VestingResult, error) bool) {
+		for employee := range employees {
+			employee, err := tenantEmployee(tenantID, employee)
+			var result VestingResult
+			if err != nil {
+				vs.employeeFailed(employee, errorTypeTenant, err)
+			} else {
+				employee = vs.applyTenantSettings(employee)
+				result, err = vs.timedCalculation(employee, asOfDate)
+				if err != nil {
+					vs.employeeFailed(employee, errorTypeValidation, err)
+				}
+			}
+			if err != nil {
+				if !yield(VestingResult{EmployeeID: employee.ID}, fmt.Errorf("employee %s: %w", employee.ID, err)) {
+					return
+				}
+				continue
+			}
+
+			vs.storeResult(employee, result)
+			if webhooks := vs.webhookDispatcher(); webhooks != nil {
+				webhooks.Enqueue(webhooks.Observe(employee, result))
+			}
+			if !yield(result, nil) {
+				return
+			}
+		}
+	}
+}
+
+// StreamResults yields cached results of the default tenant in the order the
+// IDs are produced. Like GetBatchResults, a missing ID is an error, but
+// results read before it have already been delivered and the stream ends at
+// the first miss.
+func (vs *VestingService) StreamResults(employeeIDs iter.Seq[string]) iter.Seq2[VestingResult, error] {
+	return vs.streamResults(defaultTenant, employeeIDs)
+}
+
+func (vs *VestingService) streamResults(tenantID string, employeeIDs iter.Seq[string]) iter.Seq2[VestingResult, error] {
+	return func(yield func(VestingResult, error) bool) {
+		for id := range employeeIDs {
+			result, exists := vs.getResult(tenantID, id)
+			if !exists {
+				vs.metrics.countError(errorTypeNotFound)
+				yield(VestingResult{EmployeeID: id}, fmt.Errorf("result not found for employee %s", id))
+				return
+			}
+			if !yield(result, nil) {
+				return
+			}
+		}
+	}
+}
+
+// LookupResults yields the default tenant's cached result for every ID that
+// has one and reports each ID without a result to missing instead of
+// aborting. missing may be nil when the caller only cares about found
+// results.
+func (vs *VestingService) LookupResults(employeeIDs iter.Seq[string], missing func(employeeID string)) iter.Seq2[string, VestingResult] {
+	return vs.lookupResults(defaultTenant, employeeIDs, missing)
+}
+
+func (vs *VestingService) lookupResults(tenantID string, employeeIDs iter.Seq[string], missing func(employeeID string)) iter.Seq2[string, VestingResult] {
+	return func(yield func(string, VestingResult) bool) {
+		for id := range employeeIDs {
+			result, exists := vs.getResult(tenantID, id)
+			if !exists {
+				if missing != nil {
+					missing(id)
+				}
+				continue
+			}
+			if !yield(id, result) {
+				return
+			}
+		}
+	}
+}
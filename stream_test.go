@@ -0,0 +1,135 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestStreamBatch(t *testing.T) {
+	service := NewVestingService()
+
+	employees := []Employee{
+		{
+			ID:         "stream1",
+			Name:       "Stream Employee 1",
+			StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			TotalUnits: 10000,
+			Schedule: VestingSchedule{
+				CliffMonths:   12,
+				VestingMonths: 48,
+				VestingType:   "linear",
+			},
+		},
+		{
+			ID:         "stream_bad",
+			Name:       "Invalid Units Employee",
+			StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			TotalUnits: 0,
+			Schedule: VestingSchedule{
+				CliffMonths:   12,
+				VestingMonths: 48,
+				VestingType:   "linear",
+			},
+		},
+		{
+			ID:         "stream2",
+			Name:       "Stream Employee 2",
+			StartDate:  time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC),
+			TotalUnits: 20000,
+			Schedule: VestingSchedule{
+				CliffMonths:   12,
+				VestingMonths: 48,
+				VestingType:   "linear",
+			},
+		},
+	}
+
+	asOfDate := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var yielded []string
+	var failed []string
+	for result, err := range service.StreamBatch(slices.Values(employees), asOfDate) {
+		if err != nil {
+			failed = append(failed, result.EmployeeID)
+			continue
+		}
+		yielded = append(yielded, result.EmployeeID)
+	}
+
+	if !slices.Equal(yielded, []string{"stream1", "stream2"}) {
+		t.Errorf("Expected results for stream1 and stream2, got %v", yielded)
+	}
+	if !slices.Equal(failed, []string{"stream_bad"}) {
+		t.Errorf("Expected a single failure for stream_bad, got %v", failed)
+	}
+
+	result, exists := service.GetResult("stream1")
+	if !exists {
+		t.Fatal("Streamed result was not stored in cache")
+	}
+	if result.VestedUnits != 3333 {
+		t.Errorf("Expected 3333 vested units, got %d", result.VestedUnits)
+	}
+
+	// Breaking out of the loop stops the calculation of later employees
+	service.ClearCache()
+	for range service.StreamBatch(slices.Values(employees), asOfDate) {
+		break
+	}
+	if _, exists := service.GetResult("stream2"); exists {
+		t.Error("Expected stream2 not to be calculated after an early break")
+	}
+}
+
+func TestStreamAndLookupResults(t *testing.T) {
+	service := NewVestingService()
+
+	employee := Employee{
+		ID:         "lookup1",
+		Name:       "Lookup Employee",
+		StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		TotalUnits: 10000,
+		Schedule: VestingSchedule{
+			CliffMonths:   12,
+			VestingMonths: 48,
+			VestingType:   "linear",
+		},
+	}
+
+	if err := service.ProcessBatch([]Employee{employee}, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+
+	ids := []string{"lookup1", "missing1", "lookup1", "missing2"}
+
+	// StreamResults stops at the first missing ID
+	var streamed int
+	var streamErr error
+	for _, err := range service.StreamResults(slices.Values(ids)) {
+		if err != nil {
+			streamErr = err
+			break
+		}
+		streamed++
+	}
+	if streamed != 1 || streamErr == nil {
+		t.Errorf("Expected 1 result followed by an error, got %d results and error %v", streamed, streamErr)
+	}
+
+	// LookupResults reports misses and keeps going
+	var found []string
+	var missing []string
+	for id := range service.LookupResults(slices.Values(ids), func(id string) {
+		missing = append(missing, id)
+	}) {
+		found = append(found, id)
+	}
+
+	if !slices.Equal(found, []string{"lookup1", "lookup1"}) {
+		t.Errorf("Expected lookup1 twice, got %v", found)
+	}
+	if !slices.Equal(missing, []string{"missing1", "missing2"}) {
+		t.Errorf("Expected missing1 and missing2 to be reported, got %v", missing)
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"iter"
+	"log/slog"
+	"time"
+)
+
+// defaultTenant is the tenant used by the VestingService methods that do not
+// take a tenant, and by employees with no TenantID
+const defaultTenant = ""
+
+// TenantSettings are per-company defaults applied to every calculation for
+// the tenant's employees
+type TenantSettings struct {
+	// DefaultSchedule is used for employees with no schedule of their own
+	DefaultSchedule VestingSchedule
+
+	// Rounding is used for schedules that do not set their own
+	Rounding string
+
+	// ContractualTermMonths and ExerciseWindowDays are used for option
+	// grants that do not set their own
+	ContractualTermMonths int
+	ExerciseWindowDays    int
+
+	// Calendar is used for employees with no holiday calendar of their own
+	Calendar *HolidayCalendar
+
+	// VestDates are the fixed vest dates used for employees with none of
+	// their own, such as Feb 15, May 15, Aug 15 and Nov 15
+	VestDates []MonthDay
+
+	// Location is the tenant's timezone. Employee dates are calendar dates
+	// and keep their wall-clock value in it, while as-of dates are instants
+	// and are converted to it.
+	Location *time.Location
+}
+
+// TenantService is a view of a VestingService restricted to one tenant.
+// Results, cache reads and cache clears never cross into another tenant.
+type TenantService struct {
+	vs       *VestingService
+	tenantID string
+}
+
+// RegisterTenant sets the settings for a tenant, replacing any it had
+func (vs *VestingService) RegisterTenant(tenantID string, settings TenantSettings) error {
+	if err := validateTenantSettings(tenantID, settings); err != nil {
+		vs.log().Warn("tenant settings rejected",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()))
+		return err
+	}
+
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	vs.tenants[tenantID] = settings
+	return nil
+}
+
+func validateTenantSettings(tenantID string, settings TenantSettings) error {
+	if settings.DefaultSchedule != (VestingSchedule{}) {
+		if err := ValidateSchedule(settings.DefaultSchedule); err != nil {
+			return fmt.Errorf("tenant %s default schedule: %w", tenantID, err)
+		}
+	}
+	if err := validateRounding(settings.Rounding); err != nil {
+		return fmt.Errorf("tenant %s: %w", tenantID, err)
+	}
+	if settings.ContractualTermMonths < 0 {
+		return fmt.Errorf("tenant %s: invalid contractual term: %d months", tenantID, settings.ContractualTermMonths)
+	}
+	if settings.ExerciseWindowDays < 0 {
+		return fmt.Errorf("tenant %s: invalid exercise window: %d days", tenantID, settings.ExerciseWindowDays)
+	}
+	if err := validateVestDates(settings.VestDates); err != nil {
+		return fmt.Errorf("tenant %s: %w", tenantID, err)
+	}
+	return nil
+}
+
+// Tenant returns a view of the service restricted to tenantID
+func (vs *VestingService) Tenant(tenantID string) *TenantService {
+	return &TenantService{vs: vs, tenantID: tenantID}
+}
+
+// ID returns the tenant the view is restricted to
+func (ts *TenantService) ID() string {
+	return ts.tenantID
+}
+
+// ProcessBatch calculates vesting for multiple employees of the tenant
+// concurrently. Employees without a TenantID are treated as the tenant's;
+// employees of another tenant fail the batch.
+func (ts *TenantService) ProcessBatch(employees []Employee, asOfDate time.Time) error {
+	return ts.vs.processBatch(ts.tenantID, employees, asOfDate)
+}
+
+// GetResult retrieves a vesting result of the tenant from cache
+func (ts *TenantService) GetResult(employeeID string) (VestingResult, bool) {
+	return ts.vs.getResult(ts.tenantID, employeeID)
+}
+
+// GetBatchResults returns all results of the tenant for a list of employee IDs
+func (ts *TenantService) GetBatchResults(employeeIDs []string) (map[string]VestingResult, error) {
+	return ts.vs.getBatchResults(ts.tenantID, employeeIDs)
+}
+
+// ClearCache clears the cached results of the tenant only
+func (ts *TenantService) ClearCache() {
+	ts.vs.mu.Lock()
+	size := 0
+	for key := range ts.vs.cache.results {
+		if key.TenantID == ts.tenantID {
+			delete(ts.vs.cache.results, key)
+			size++
+		}
+	}
+	for key := range ts.vs.cache.employees {
+		if key.TenantID == ts.tenantID {
+			delete(ts.vs.cache.employees, key)
+		}
+	}
+	ts.vs.mu.Unlock()
+
+	ts.vs.log().Info("cache cleared",
+		slog.String("tenant_id", ts.tenantID),
+		slog.Int("results", size))
+}
+
+// StreamBatch is VestingService.StreamBatch for the tenant's employees
+func (ts *TenantService) StreamBatch(employees iter.Seq[Employee], asOfDate time.Time) iter.Seq2[VestingResult, error] {
+	return ts.vs.streamBatch(ts.tenantID, employees, asOfDate)
+}
+
+// StreamResults is VestingService.StreamResults for the tenant's results
+func (ts *TenantService) StreamResults(employeeIDs iter.Seq[string]) iter.Seq2[VestingResult, error] {
+	return ts.vs.streamResults(ts.tenantID, employeeIDs)
+}
+
+// LookupResults is VestingService.LookupResults for the tenant's results
+func (ts *TenantService) LookupResults(employeeIDs iter.Seq[string], missing func(employeeID string)) iter.Seq2[string, VestingResult] {
+	return ts.vs.lookupResults(ts.tenantID, employeeIDs, missing)
+}
+
+// UpcomingVests is VestingService.UpcomingVests for the tenant's employees
+func (ts *TenantService) UpcomingVests(asOfDate time.Time, days int) ([]CalendarDay, error) {
+	return ts.vs.upcomingVests(ts.tenantID, asOfDate, days)
+}
+
+// ExpiringWindows is VestingService.ExpiringWindows for the tenant's
+// employees
+func (ts *TenantService) ExpiringWindows(asOfDate time.Time, days int) ([]ExpiringGrant, error) {
+	return ts.vs.expiringWindows(ts.tenantID, asOfDate, days)
+}
+
+// tenantEmployee assigns an employee without a tenant to tenantID and
+// rejects an employee that belongs to another tenant
+func tenantEmployee(tenantID string, employee Employee) (Employee, error) {
+	if employee.TenantID == "" {
+		employee.TenantID = tenantID
+	}
+	if employee.TenantID != tenantID {
+		return employee, fmt.Errorf("employee %s belongs to tenant %s, not %s", employee.ID, employee.TenantID, tenantID)
+	}
+	return employee, nil
+}
+
+// tenantSettings returns the settings registered for a tenant
+func (vs *VestingService) tenantSettings(tenantID string) (TenantSettings, bool) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	settings, exists := vs.tenants[tenantID]
+	return settings, exists
+}
+
+// applyTenantSettings fills in the employee's missing schedule, rounding,
+// option terms, holiday calendar and vest dates from the tenant defaults and
+// moves its dates into the tenant's timezone. Applying it more than once has
+// no further effect.
+func (vs *VestingService) applyTenantSettings(employee Employee) Employee {
+	settings, exists := vs.tenantSettings(employee.TenantID)
+	if !exists {
+		return employee
+	}
+
+	if employee.Schedule.VestingType == "" && employee.Schedule.VestingMonths == 0 {
+		employee.Schedule = settings.DefaultSchedule
+	}
+	if employee.Schedule.Rounding == "" {
+		employee.Schedule.Rounding = settings.Rounding
+	}
+	if employee.ContractualTermMonths == 0 {
+		employee.ContractualTermMonths = settings.ContractualTermMonths
+	}
+	if employee.ExerciseWindowDays == 0 {
+		employee.ExerciseWindowDays = settings.ExerciseWindowDays
+	}
+	if employee.Calendar == nil {
+		employee.Calendar = settings.Calendar
+	}
+	if employee.VestDates == nil {
+		employee.VestDates = settings.VestDates
+	}
+	if settings.Location != nil {
+		employee.StartDate = inLocation(employee.StartDate, settings.Location)
+		employee.VestingCommencementDate = inLocation(employee.VestingCommencementDate, settings.Location)
+		employee.GrantDate = inLocation(employee.GrantDate, settings.Location)
+		employee.BoardApprovalDate = inLocation(employee.BoardApprovalDate, settings.Location)
+		employee.TerminationDate = inLocation(employee.TerminationDate, settings.Location)
+	}
+	return employee
+}
+
+// tenantTime converts an instant to the tenant's timezone
+func (vs *VestingService) tenantTime(tenantID string, t time.Time) time.Time {
+	if settings, exists := vs.tenantSettings(tenantID); exists && settings.Location != nil {
+		return t.In(settings.Location)
+	}
+	return t
+}
+
+// inLocation keeps the wall-clock value of a calendar date in loc
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	year, month, day := t.Date()
+	hour, minute, second := t.Clock()
+	return time.Date(year, month, day, hour, minute, second, t.Nanosecond(), loc)
+}
+
+// roundingName returns the rounding applied for a schedule's setting
+func roundingName(rounding string) string {
+	if rounding == "" {
+		return "floor"
+	}
+	return rounding
+}
+
+// remainderName returns where a schedule's rounding remainder vests, which
+// is empty unless its tranches are rounded on their own
+func remainderName(schedule VestingSchedule) string {
+	if schedule.Rounding != "tranche-floor" {
+		return ""
+	}
+	if schedule.Remainder == "" {
+		return "last"
+	}
+	return schedule.Remainder
+}
+
+func validateRounding(rounding string) error {
+	if rounding != "" && rounding != "floor" && rounding != "half-even" && rounding != "tranche-floor" {
+		return fmt.Errorf("invalid rounding: %s", rounding)
+	}
+	return nil
+}
+
+// validateRemainder ensures a schedule only places the rounding remainder
+// when its tranches are rounded on their own
+func validateRemainder(schedule VestingSchedule) error {
+	switch schedule.Remainder {
+	case "":
+		return nil
+	case "first", "last":
+		if schedule.Rounding != "tranche-floor" {
+			return fmt.Errorf("remainder placement requires tranche-floor rounding, not %s", roundingName(schedule.Rounding))
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid remainder placement: %s", schedule.Remainder)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTenantIsolation(t *testing.T) {
+	service := NewVestingService()
+	companyA := service.Tenant("company_a")
+	companyB := service.Tenant("company_b")
+
+	employee := Employee{
+		ID:         "emp001",
+		StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		TotalUnits: 10000,
+		Schedule:   VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"},
+	}
+	other := employee
+	other.TotalUnits = 20000
+
+	asOfDate := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := companyA.ProcessBatch([]Employee{employee}, asOfDate); err != nil {
+		t.Fatalf("ProcessBatch for company_a failed: %v", err)
+	}
+	if err := companyB.ProcessBatch([]Employee{other}, asOfDate); err != nil {
+		t.Fatalf("ProcessBatch for company_b failed: %v", err)
+	}
+
+	resultA, existsA := companyA.GetResult("emp001")
+	resultB, existsB := companyB.GetResult("emp001")
+	if !existsA || !existsB {
+		t.Fatal("Expected a result for emp001 in both tenants")
+	}
+	if resultA.VestedUnits != 3333 || resultB.VestedUnits != 6666 {
+		t.Errorf("Expected 3333 and 6666 vested units, got %d and %d", resultA.VestedUnits, resultB.VestedUnits)
+	}
+	if resultA.TenantID != "company_a" || resultB.TenantID != "company_b" {
+		t.Errorf("Results carry the wrong tenants: %q and %q", resultA.TenantID, resultB.TenantID)
+	}
+
+	// The default tenant sees neither
+	if _, exists := service.GetResult("emp001"); exists {
+		t.Error("Default tenant can read another tenant's result")
+	}
+	if _, err := service.GetBatchResults([]string{"emp001"}); err == nil {
+		t.Error("Default tenant batch read should not find another tenant's result")
+	}
+
+	// Employees of another tenant are rejected
+	foreign := employee
+	foreign.ID = "emp002"
+	foreign.TenantID = "company_b"
+	if err := companyA.ProcessBatch([]Employee{foreign}, asOfDate); err == nil {
+		t.Error("Expected company_a to reject an employee of company_b")
+	}
+	if _, exists := companyB.GetResult("emp002"); exists {
+		t.Error("Rejected employee was stored under its own tenant")
+	}
+
+	// Clearing one tenant leaves the other alone
+	companyA.ClearCache()
+	if _, exists := companyA.GetResult("emp001"); exists {
+		t.Error("company_a result survived its cache clear")
+	}
+	if _, exists := companyB.GetResult("emp001"); !exists {
+		t.Error("company_b result was removed by company_a's cache clear")
+	}
+
+	var missing []string
+	for range companyA.LookupResults(slices.Values([]string{"emp001"}), func(id string) {
+		missing = append(missing, id)
+	}) {
+		t.Error("Lookup found a result after the tenant's cache was cleared")
+	}
+	if len(missing) != 1 {
+		t.Errorf("Expected emp001 to be reported missing, got %v", missing)
+	}
+
+	service.ClearCache()
+	if _, exists := companyB.GetResult("emp001"); exists {
+		t.Error("Service-wide cache clear left company_b's result")
+	}
+}
+
+func TestTenantSettings(t *testing.T) {
+	service := NewVestingService()
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("Timezone data not available: %v", err)
+	}
+
+	err = service.RegisterTenant("acme", TenantSettings{
+		DefaultSchedule: VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"},
+		Rounding:        "half-even",
+		Location:        newYork,
+	})
+	if err != nil {
+		t.Fatalf("RegisterTenant failed: %v", err)
+	}
+
+	acme := service.Tenant("acme")
+	employee := Employee{
+		ID:         "acme1",
+		StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		TotalUnits: 10000,
+	}
+
+	// Midnight UTC on Jan 1 is still Dec 31 in New York
+	asOfDate := time.Date(2023, 1, 1, 3, 0, 0, 0, time.UTC)
+	if err := acme.ProcessBatch([]Employee{employee}, asOfDate); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+
+	result, exists := acme.GetResult("acme1")
+	if !exists {
+		t.Fatal("Result not found")
+	}
+
+	// 24 months with the default schedule: 10000 * 12/36 = 3333.33, and
+	// half-even rounding gives the same whole units as floor here
+	if result.VestedUnits != 3333 {
+		t.Errorf("Expected 3333 vested units, got %d", result.VestedUnits)
+	}
+	if result.AsOfDate.Location() != newYork || result.AsOfDate.Day() != 31 {
+		t.Errorf("Expected the as-of date in New York on Dec 31, got %s", result.AsOfDate)
+	}
+
+	// Half-even rounds 2/3 of a unit up where floor drops it
+	small := Employee{
+		TenantID:   "acme",
+		ID:         "acme2",
+		StartDate:  employee.StartDate,
+		TotalUnits: 2,
+		Schedule:   VestingSchedule{CliffMonths: 0, VestingMonths: 3, VestingType: "linear"},
+	}
+	result, err = service.calculateVesting(small, time.Date(2021, 2, 1, 0, 0, 0, 0, newYork))
+	if err != nil {
+		t.Fatalf("calculateVesting failed: %v", err)
+	}
+	if result.VestedUnits != 1 {
+		t.Errorf("Expected half-even rounding to vest 1 unit, got %d", result.VestedUnits)
+	}
+
+	if err := service.RegisterTenant("bad", TenantSettings{Rounding: "ceiling"}); err == nil {
+		t.Error("Expected error for an invalid rounding")
+	}
+	if err := service.RegisterTenant("bad", TenantSettings{DefaultSchedule: VestingSchedule{VestingMonths: 12}}); err == nil {
+		t.Error("Expected error for an invalid default schedule")
+	}
+}
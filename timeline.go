@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimelineStep is the distance between two points of a timeline
+type TimelineStep struct {
+	Months int
+	Days   int
+}
+
+// Common timeline steps
+var (
+	DailyStep   = TimelineStep{Days: 1}
+	WeeklyStep  = TimelineStep{Days: 7}
+	MonthlyStep = TimelineStep{Months: 1}
+)
+
+// TimelinePoint is the vesting state of an employee at a single date
+type TimelinePoint struct {
+	Date          time.Time
+	VestedUnits   int
+	UnvestedUnits int
+}
+
+// Timeline returns the vested and unvested units at from and at every step
+// after it up to and including to. Each point matches calculateVesting for
+// the same date, but months are counted once for the whole range and the
+// schedule is only evaluated when the month count changes.
+func (vs *VestingService) Timeline(employee Employee, from, to time.Time, step TimelineStep) ([]TimelinePoint, error) {
+	if employee.TotalUnits <= 0 {
+		return nil, fmt.Errorf("invalid total units: %d", employee.TotalUnits)
+	}
+	if step.Months < 0 || step.Days < 0 || (step.Months == 0 && step.Days == 0) {
+		return nil, fmt.Errorf("invalid timeline step: %d months, %d days", step.Months, step.Days)
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("timeline end %s is before start %s", to.Format("2006-01-02"), from.Format("2006-01-02"))
+	}
+
+	if err := validateTermChanges(employee); err != nil {
+		return nil, err
+	}
+	if err := validateGrantDates(employee); err != nil {
+		return nil, err
+	}
+
+	employee = vs.applyTenantSettings(employee)
+	if err := validateFixedVestDates(employee); err != nil {
+		return nil, err
+	}
+
+	var points []TimelinePoint
+	grant, conversion := grantAt(employee, from)
+	exchanged := exchangedBy(employee, from)
+	segments := vestingSegments(grant)
+	segment := segments[0]
+	counter := newMonthCounter(segment.employee)
+	lastMonths, vestedUnits := -1, 0
+
+	for i := 0; ; i++ {
+		// Offsets are taken from the start of the range so that month
+		// steps do not drift after passing a short month
+		date := from.AddDate(0, i*step.Months, i*step.Days)
+		if date.After(to) {
+			break
+		}
+
+		if !exchanged && exchangedBy(employee, date) {
+			// The replacement grant takes over from the exchange
+			grant, conversion = grantAt(employee, date)
+			exchanged = true
+			segments = vestingSegments(grant)
+			segment = segments[0]
+			counter = newMonthCounter(segment.employee)
+			lastMonths = -1
+		}
+
+		cutoff := vestingCutoff(employee, date)
+		if active := activeSegment(segments, cutoff); !active.from.Equal(segment.from) {
+			// An amendment took effect, so months restart from it
+			segment = active
+			counter = newMonthCounter(segment.employee)
+			lastMonths = -1
+		}
+
+		months := counter.advance(cutoff)
+		if months != lastMonths {
+			vestedUnits = segment.baseUnits + vestedForMonths(segment.employee, months, nil)
+			lastMonths = months
+		}
+
+		basis := vs.splitBasis(employee, date, time.Time{})
+		converted := conversion.Units(vestedUnits)
+		points = append(points, TimelinePoint{
+			Date:          date,
+			VestedUnits:   basis.Units(converted),
+			UnvestedUnits: basis.Units(conversion.Units(grant.TotalUnits)) - basis.Units(converted),
+		})
+	}
+
+	return points, nil
+}
+
+// monthCounter tracks countMonths(employee, t) for non-decreasing values of
+// t without recounting the months it has already passed
+type monthCounter struct {
+	employee Employee
+	boundary time.Time
+	months   int
+}
+
+func newMonthCounter(employee Employee) *monthCounter {
+	return &monthCounter{employee: employee, boundary: employee.StartDate}
+}
+
+// advance returns countMonths(employee, t). t must not be before the value
+// passed to the previous call.
+func (c *monthCounter) advance(t time.Time) int {
+	for vestDate(c.employee, c.boundary).Before(t) {
+		c.months++
+		c.boundary = nextBoundary(c.employee.StartDate, c.boundary, c.months, c.employee.Schedule.VestDay)
+	}
+	return c.months
+}
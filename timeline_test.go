@@ -0,0 +1,141 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimelineMatchesCalculateVesting(t *testing.T) {
+	service := NewVestingService()
+
+	tests := []struct {
+		name     string
+		employee Employee
+		from     time.Time
+		to       time.Time
+		step     TimelineStep
+	}{
+		{
+			name: "Linear monthly",
+			employee: Employee{
+				ID:         "timeline_linear",
+				Name:       "Linear Employee",
+				StartDate:  time.Date(2021, 1, 31, 0, 0, 0, 0, time.UTC),
+				TotalUnits: 48000,
+				Schedule: VestingSchedule{
+					CliffMonths:   12,
+					VestingMonths: 48,
+					VestingType:   "linear",
+				},
+			},
+			from: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+			step: MonthlyStep,
+		},
+		{
+			name: "Backloaded weekly",
+			employee: Employee{
+				ID:         "timeline_backloaded",
+				Name:       "Backloaded Employee",
+				StartDate:  time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC),
+				TotalUnits: 60000,
+				Schedule: VestingSchedule{
+					CliffMonths:   12,
+					VestingMonths: 48,
+					VestingType:   "backloaded",
+				},
+			},
+			from: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC),
+			step: WeeklyStep,
+		},
+		{
+			name: "Unknown type daily",
+			employee: Employee{
+				ID:         "timeline_unknown",
+				Name:       "Unknown Type Employee",
+				StartDate:  time.Date(2022, 3, 15, 0, 0, 0, 0, time.UTC),
+				TotalUnits: 1000,
+				Schedule: VestingSchedule{
+					CliffMonths:   6,
+					VestingMonths: 36,
+					VestingType:   "custom",
+				},
+			},
+			from: time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC),
+			step: DailyStep,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points, err := service.Timeline(tt.employee, tt.from, tt.to, tt.step)
+			if err != nil {
+				t.Fatalf("Timeline failed: %v", err)
+			}
+			if len(points) == 0 {
+				t.Fatal("Expected timeline points")
+			}
+
+			for _, point := range points {
+				expected, err := service.calculateVesting(tt.employee, point.Date)
+				if err != nil {
+					t.Fatalf("calculateVesting failed: %v", err)
+				}
+				if point.VestedUnits != expected.VestedUnits || point.UnvestedUnits != expected.UnvestedUnits {
+					t.Errorf("%s: timeline has %d/%d vested/unvested, calculateVesting has %d/%d",
+						point.Date.Format("2006-01-02"), point.VestedUnits, point.UnvestedUnits,
+						expected.VestedUnits, expected.UnvestedUnits)
+				}
+			}
+		})
+	}
+}
+
+func TestTimelineRange(t *testing.T) {
+	service := NewVestingService()
+
+	employee := Employee{
+		ID:         "timeline_range",
+		Name:       "Range Employee",
+		StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		TotalUnits: 10000,
+		Schedule: VestingSchedule{
+			CliffMonths:   12,
+			VestingMonths: 48,
+			VestingType:   "linear",
+		},
+	}
+
+	from := time.Date(2022, 1, 31, 0, 0, 0, 0, time.UTC)
+	points, err := service.Timeline(employee, from, time.Date(2022, 5, 31, 0, 0, 0, 0, time.UTC), MonthlyStep)
+	if err != nil {
+		t.Fatalf("Timeline failed: %v", err)
+	}
+
+	// Steps are offsets from the range start, so February does not pull
+	// the following points back from the 31st
+	expected := []time.Time{
+		from,
+		time.Date(2022, 3, 3, 0, 0, 0, 0, time.UTC),
+		time.Date(2022, 3, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2022, 5, 31, 0, 0, 0, 0, time.UTC),
+	}
+	if len(points) != len(expected) {
+		t.Fatalf("Expected %d points, got %d", len(expected), len(points))
+	}
+	for i, point := range points {
+		if !point.Date.Equal(expected[i]) {
+			t.Errorf("Point %d: expected %s, got %s", i, expected[i].Format("2006-01-02"), point.Date.Format("2006-01-02"))
+		}
+	}
+
+	if _, err := service.Timeline(employee, from, from, TimelineStep{}); err == nil {
+		t.Error("Expected error for a zero step")
+	}
+	if _, err := service.Timeline(employee, from, from.AddDate(0, 0, -1), MonthlyStep); err == nil {
+		t.Error("Expected error when the range ends before it starts")
+	}
+}
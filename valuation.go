@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// BlackScholesInputs are the assumptions for valuing a European call option.
+// Rates, yields and volatility are annualized decimals, and ExpectedTerm is
+// in years.
+type BlackScholesInputs struct {
+	StockPrice    float64
+	StrikePrice   float64
+	ExpectedTerm  float64
+	Volatility    float64
+	RiskFreeRate  float64
+	DividendYield float64
+}
+
+// GrantValuation holds the market assumptions used to value an employee's
+// grant. The strike comes from the grant itself. When ExpectedTermYears is
+// zero the simplified method is used with ContractualTermYears.
+type GrantValuation struct {
+	StockPrice           float64
+	Volatility           float64
+	RiskFreeRate         float64
+	DividendYield        float64
+	ContractualTermYears float64
+	ExpectedTermYears    float64
+}
+
+// expectedTermUnits is the nominal grant size used to weight a schedule's
+// tranches when only the schedule is known
+const expectedTermUnits = 1_000_000
+
+// BlackScholesCall returns the fair value of one call option. A zero strike
+// values a full-value award such as an RSU, which is worth the stock price
+// less the dividends forgone over the term.
+func BlackScholesCall(in BlackScholesInputs) (float64, error) {
+	for _, input := range []struct {
+		name  string
+		value float64
+	}{
+		{"stock price", in.StockPrice},
+		{"strike price", in.StrikePrice},
+		{"expected term", in.ExpectedTerm},
+		{"volatility", in.Volatility},
+		{"risk-free rate", in.RiskFreeRate},
+		{"dividend yield", in.DividendYield},
+	} {
+		if math.IsNaN(input.value) || math.IsInf(input.value, 0) {
+			return 0, fmt.Errorf("invalid %s: %f", input.name, input.value)
+		}
+	}
+	if in.StockPrice <= 0 {
+		return 0, fmt.Errorf("stock price must be positive")
+	}
+	if in.StrikePrice < 0 {
+		return 0, fmt.Errorf("strike price cannot be negative")
+	}
+	if in.ExpectedTerm <= 0 {
+		return 0, fmt.Errorf("expected term must be positive")
+	}
+	if in.Volatility <= 0 {
+		return 0, fmt.Errorf("volatility must be positive")
+	}
+
+	discountedStock := in.StockPrice * math.Exp(-in.DividendYield*in.ExpectedTerm)
+	if in.StrikePrice == 0 {
+		return discountedStock, nil
+	}
+
+	discountedStrike := in.StrikePrice * math.Exp(-in.RiskFreeRate*in.ExpectedTerm)
+	volTerm := in.Volatility * math.Sqrt(in.ExpectedTerm)
+	d1 := (math.Log(in.StockPrice/in.StrikePrice) +
+		(in.RiskFreeRate-in.DividendYield+in.Volatility*in.Volatility/2)*in.ExpectedTerm) / volTerm
+	d2 := d1 - volTerm
+
+	return discountedStock*normalCDF(d1) - discountedStrike*normalCDF(d2), nil
+}
+
+// SimplifiedExpectedTerm returns the SEC Staff Accounting Bulletin 107
+// "simplified method" expected term in years: the midpoint between the
+// weighted average vesting term of the schedule's tranches and the
+// contractual term
+func SimplifiedExpectedTerm(schedule VestingSchedule, contractualTermYears float64) (float64, error) {
+	if err := ValidateSchedule(schedule); err != nil {
+		return 0, err
+	}
+
+	_, vested := monthlyVesting(Employee{TotalUnits: expectedTermUnits, Schedule: schedule}, grantBasis)
+	return simplifiedTerm(vested, contractualTermYears)
+}
+
+// simplifiedTerm returns the simplified method expected term for tranches
+// given as the units vested by the end of each service month
+func simplifiedTerm(vested []int, contractualTermYears float64) (float64, error) {
+	weightedMonths := 0.0
+	for month := 1; month < len(vested); month++ {
+		weightedMonths += float64(vested[month]-vested[month-1]) * float64(month)
+	}
+	vestingTermYears := weightedMonths / float64(vested[len(vested)-1]) / 12
+
+	if contractualTermYears < vestingTermYears {
+		return 0, fmt.Errorf("contractual term %.2f years is shorter than the vesting term %.2f years",
+			contractualTermYears, vestingTermYears)
+	}
+
+	return (vestingTermYears + contractualTermYears) / 2, nil
+}
+
+// ValueGrant calculates the grant-date fair value per unit of the employee's
+// grant and attaches it to the employee so expense attribution picks it up.
+// An exchanged grant is valued as its replacement, per replacement unit, and
+// the expected term is weighted by the tranches of the grant as amended.
+func ValueGrant(employee *Employee, valuation GrantValuation) (float64, error) {
+	if err := validateTermChanges(*employee); err != nil {
+		return 0, fmt.Errorf("employee %s: %w", employee.ID, err)
+	}
+	grant, conversion := grantInForce(*employee)
+
+	term := valuation.ExpectedTermYears
+	if term == 0 {
+		if err := ValidateSchedule(grant.Schedule); err != nil {
+			return 0, fmt.Errorf("employee %s: %w", employee.ID, err)
+		}
+		_, vested := monthlyVesting(vestingStart(grant), conversion)
+		if len(vested) < 2 {
+			return 0, fmt.Errorf("employee %s: grant has no units to vest", employee.ID)
+		}
+
+		var err error
+		term, err = simplifiedTerm(vested, valuation.ContractualTermYears)
+		if err != nil {
+			return 0, fmt.Errorf("employee %s: %w", employee.ID, err)
+		}
+	}
+
+	fairValue, err := BlackScholesCall(BlackScholesInputs{
+		StockPrice:    valuation.StockPrice,
+		StrikePrice:   grant.StrikePrice,
+		ExpectedTerm:  term,
+		Volatility:    valuation.Volatility,
+		RiskFreeRate:  valuation.RiskFreeRate,
+		DividendYield: valuation.DividendYield,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("employee %s: %w", employee.ID, err)
+	}
+
+	employee.FairValuePerUnit = fairValue
+	return fairValue, nil
+}
+
+func normalCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBlackScholesCall(t *testing.T) {
+	tests := []struct {
+		name        string
+		inputs      BlackScholesInputs
+		expected    float64
+		shouldError bool
+	}{
+		{
+			name: "At the money",
+			inputs: BlackScholesInputs{
+				StockPrice:   100,
+				StrikePrice:  100,
+				ExpectedTerm: 1,
+				Volatility:   0.2,
+				RiskFreeRate: 0.05,
+			},
+			expected: 10.4506,
+		},
+		{
+			name: "With dividend yield",
+			inputs: BlackScholesInputs{
+				StockPrice:    42,
+				StrikePrice:   40,
+				ExpectedTerm:  0.5,
+				Volatility:    0.2,
+				RiskFreeRate:  0.1,
+				DividendYield: 0.03,
+			},
+			expected: 4.2823,
+		},
+		{
+			name: "Textbook example",
+			inputs: BlackScholesInputs{
+				StockPrice:   42,
+				StrikePrice:  40,
+				ExpectedTerm: 0.5,
+				Volatility:   0.2,
+				RiskFreeRate: 0.1,
+			},
+			expected: 4.7594,
+		},
+		{
+			name: "Zero strike",
+			inputs: BlackScholesInputs{
+				StockPrice:    50,
+				ExpectedTerm:  2,
+				Volatility:    0.3,
+				RiskFreeRate:  0.04,
+				DividendYield: 0.01,
+			},
+			expected: 50 * math.Exp(-0.02),
+		},
+		{
+			name: "Zero volatility",
+			inputs: BlackScholesInputs{
+				StockPrice:   100,
+				StrikePrice:  100,
+				ExpectedTerm: 1,
+			},
+			shouldError: true,
+		},
+		{
+			name: "NaN rate",
+			inputs: BlackScholesInputs{
+				StockPrice:   100,
+				StrikePrice:  100,
+				ExpectedTerm: 1,
+				Volatility:   0.2,
+				RiskFreeRate: math.NaN(),
+			},
+			shouldError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := BlackScholesCall(tt.inputs)
+			if tt.shouldError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if math.Abs(value-tt.expected) > 0.0001 {
+				t.Errorf("Expected %.4f, got %.4f", tt.expected, value)
+			}
+		})
+	}
+}
+
+func TestSimplifiedExpectedTerm(t *testing.T) {
+	// Monthly tranches in months 13 to 48 average 30.5 months
+	schedule := VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"}
+	term, err := SimplifiedExpectedTerm(schedule, 10)
+	if err != nil {
+		t.Fatalf("SimplifiedExpectedTerm failed: %v", err)
+	}
+	expected := (30.5/12 + 10) / 2
+	if math.Abs(term-expected) > 0.001 {
+		t.Errorf("Expected %.4f years, got %.4f", expected, term)
+	}
+
+	if _, err := SimplifiedExpectedTerm(schedule, 2); err == nil {
+		t.Error("Expected error for a contractual term shorter than vesting")
+	}
+	if _, err := SimplifiedExpectedTerm(VestingSchedule{VestingMonths: 12, VestingType: "cliff"}, 10); err == nil {
+		t.Error("Expected error for an invalid schedule")
+	}
+}
+
+func TestValueGrantFlowsIntoExpense(t *testing.T) {
+	service := NewVestingService()
+
+	employee := Employee{
+		ID:          "valued",
+		StartDate:   time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		TotalUnits:  12000,
+		StrikePrice: 10,
+		Schedule:    VestingSchedule{CliffMonths: 0, VestingMonths: 12, VestingType: "linear"},
+	}
+
+	fairValue, err := ValueGrant(&employee, GrantValuation{
+		StockPrice:           10,
+		Volatility:           0.5,
+		RiskFreeRate:         0.04,
+		ContractualTermYears: 10,
+	})
+	if err != nil {
+		t.Fatalf("ValueGrant failed: %v", err)
+	}
+	if fairValue <= 0 || fairValue >= 10 || employee.FairValuePerUnit != fairValue {
+		t.Fatalf("Unexpected fair value %.4f attached as %.4f", fairValue, employee.FairValuePerUnit)
+	}
+
+	report, err := service.AttributeExpense([]Employee{employee}, nil, "straight-line")
+	if err != nil {
+		t.Fatalf("AttributeExpense failed: %v", err)
+	}
+	if report.TotalExpense != roundCents(12000*fairValue) {
+		t.Errorf("Expected total expense %.2f, got %.2f", roundCents(12000*fairValue), report.TotalExpense)
+	}
+}
+
+func TestValueGrantUsesGrantInForce(t *testing.T) {
+	valuation := GrantValuation{StockPrice: 5, Volatility: 0.5, RiskFreeRate: 0.04, ContractualTermYears: 10}
+	employee := Employee{
+		ID:          "changed",
+		StartDate:   time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		TotalUnits:  48000,
+		StrikePrice: 10,
+		Schedule:    VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"},
+	}
+
+	// The replacement is struck at 4 and vests monthly over 24 months, whose
+	// tranches average 12.5 months
+	exchanged := employee
+	if err := exchanged.ExchangeGrant(GrantExchange{EffectiveDate: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+		Numerator: 1, Denominator: 2, StrikePrice: 4,
+		ResetSchedule: &VestingSchedule{CliffMonths: 0, VestingMonths: 24, VestingType: "linear"}}); err != nil {
+		t.Fatalf("ExchangeGrant failed: %v", err)
+	}
+	fairValue, err := ValueGrant(&exchanged, valuation)
+	if err != nil {
+		t.Fatalf("ValueGrant failed: %v", err)
+	}
+	expected, err := BlackScholesCall(BlackScholesInputs{StockPrice: 5, StrikePrice: 4, ExpectedTerm: (12.5/12 + 10) / 2,
+		Volatility: 0.5, RiskFreeRate: 0.04})
+	if err != nil {
+		t.Fatalf("BlackScholesCall failed: %v", err)
+	}
+	if math.Abs(fairValue-expected) > 0.0001 {
+		t.Errorf("Expected the replacement valued at %.4f, got %.4f", expected, fairValue)
+	}
+
+	// Spreading the grant over 72 months lengthens the expected term, which
+	// raises the value of the option
+	original, err := ValueGrant(&employee, valuation)
+	if err != nil {
+		t.Fatalf("ValueGrant failed: %v", err)
+	}
+	amended := employee
+	if err := amended.AmendSchedule(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		VestingSchedule{CliffMonths: 0, VestingMonths: 60, VestingType: "linear"}); err != nil {
+		t.Fatalf("AmendSchedule failed: %v", err)
+	}
+	extended, err := ValueGrant(&amended, valuation)
+	if err != nil {
+		t.Fatalf("ValueGrant failed: %v", err)
+	}
+	if extended <= original {
+		t.Errorf("Expected the amended grant worth more than %.4f, got %.4f", original, extended)
+	}
+}
+
+func TestSimplifiedExpectedTermBackloaded(t *testing.T) {
+	// 10% at month 12, then each month adds a twelfth of the current year's
+	// percentage until the year end brings the grant to 30%, 60% and 100%:
+	// tranches weighted by month average 32.7 months
+	schedule := VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "backloaded"}
+	term, err := SimplifiedExpectedTerm(schedule, 10)
+	if err != nil {
+		t.Fatalf("SimplifiedExpectedTerm failed: %v", err)
+	}
+	expected := (32.7/12 + 10) / 2
+	if math.Abs(term-expected) > 0.001 {
+		t.Errorf("Expected %.4f years, got %.4f", expected, term)
+	}
+}
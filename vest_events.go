@@ -0,0 +1,137 @@
+package main
+
+import (
+	"time"
+)
+
+// VestEvent is a change in an employee's vested units. Units vest at the end
+// of Date: calculateVesting as of Date does not include them, and any later
+// as-of date does.
+type VestEvent struct {
+	EmployeeID      string
+	Date            time.Time
+	Units           int
+	CumulativeUnits int
+
+	// Cliff marks the first event of a schedule with a cliff
+	Cliff bool
+
+	// Final marks the event that brings the employee to full vesting
+	Final bool
+
+	// Exchange marks the conversion of the vested units when the grant is
+	// exchanged. Its Units are negative when units are cancelled.
+	Exchange bool
+
+	// Split marks the restatement of the vested units when the stock splits.
+	// Its Units are negative for a reverse split.
+	Split bool
+}
+
+// backloadedYears is the number of yearly percentages in a backloaded schedule
+const backloadedYears = 4
+
+// vestEvents returns every vest event of the employee's grant in date order.
+// Month n of the schedule is counted by monthsBetween from just after the
+// (n-1)th monthly boundary, so that boundary is the event date, moved to a
+// business day when the schedule has a business-day convention. Events on or
+// after the termination date are dropped. Each schedule amendment starts
+// counting months again from its effective date, and after an exchange the
+// events are those of the replacement grant.
+func vestEvents(employee Employee) []VestEvent {
+	if employee.TotalUnits <= 0 {
+		return nil
+	}
+
+	grant := employee
+	grant.Exchange = nil
+	if employee.Exchange != nil && !terminatedBy(employee, employee.Exchange.EffectiveDate) {
+		return exchangeEvents(employee, vestEvents(grant))
+	}
+
+	segments := vestingSegments(grant)
+	if len(segments) == 1 {
+		return scheduleEvents(segments[0].employee)
+	}
+
+	var events []VestEvent
+	for i, segment := range segments {
+		for _, event := range scheduleEvents(segment.employee) {
+			if i+1 < len(segments) && !event.Date.Before(segments[i+1].from) {
+				break
+			}
+			event.CumulativeUnits += segment.baseUnits
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// scheduleEvents returns the vest events of a single schedule
+func scheduleEvents(employee Employee) []VestEvent {
+	if employee.TotalUnits <= 0 {
+		return nil
+	}
+
+	horizon := scheduleHorizon(employee.Schedule)
+
+	var events []VestEvent
+	boundary := employee.StartDate
+
+	// Units a schedule without a cliff vests at month 0 are counted from
+	// zero, so they vest with the first month on the start date
+	previous := 0
+	final := false
+
+	for months := 1; months <= horizon; months++ {
+		date := vestDate(employee, boundary)
+		if terminatedBy(employee, date) {
+			break
+		}
+
+		vested := vestedForMonths(employee, months, nil)
+		if vested > previous {
+			isFinal := !final && vested == employee.TotalUnits
+			final = final || isFinal
+			if n := len(events); n > 0 && events[n-1].Date.Equal(date) {
+				// Months accrued to the same fixed vest date vest together
+				events[n-1].Units += vested - previous
+				events[n-1].CumulativeUnits = vested
+				events[n-1].Final = events[n-1].Final || isFinal
+			} else {
+				events = append(events, VestEvent{
+					EmployeeID:      employee.ID,
+					Date:            date,
+					Units:           vested - previous,
+					CumulativeUnits: vested,
+					Cliff:           len(events) == 0 && employee.Schedule.CliffMonths > 0,
+					Final:           isFinal,
+				})
+			}
+			previous = vested
+		}
+
+		boundary = nextBoundary(employee.StartDate, boundary, months, employee.Schedule.VestDay)
+	}
+
+	return events
+}
+
+// scheduleHorizon returns a month count after which the schedule no longer
+// changes. Backloaded years are counted from the cliff, so they can run past
+// VestingMonths, though never past the whole grant.
+func scheduleHorizon(schedule VestingSchedule) int {
+	return schedule.VestingMonths + schedule.CliffMonths + 12*backloadedYears
+}
+
+// eventsBetween returns the events dated on or after from and before to,
+// which are exactly the units calculateVesting gains between the two dates
+func eventsBetween(events []VestEvent, from, to time.Time) []VestEvent {
+	var window []VestEvent
+	for _, event := range events {
+		if !event.Date.Before(from) && event.Date.Before(to) {
+			window = append(window, event)
+		}
+	}
+	return window
+}
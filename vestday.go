@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// MonthDay is a day of the year a company vests every grant on, such as
+// Feb 15. Days past the end of a month fall on its last day, so Feb 29 is
+// Feb 28 outside leap years.
+type MonthDay struct {
+	Month time.Month
+	Day   int
+}
+
+// validateVestDay ensures a schedule's vest-day rule is known
+func validateVestDay(vestDay string) error {
+	if vestDay != "" && vestDay != "anniversary" && vestDay != "fixed" {
+		return fmt.Errorf("invalid vest day: %s", vestDay)
+	}
+	return nil
+}
+
+// validateVestDates ensures company vest dates are days of the year
+func validateVestDates(dates []MonthDay) error {
+	for _, date := range dates {
+		if date.Month < time.January || date.Month > time.December {
+			return fmt.Errorf("invalid vest date: month %d", date.Month)
+		}
+		// 2024 is a leap year, so Feb 29 is accepted
+		if lastDay := time.Date(2024, date.Month+1, 0, 0, 0, 0, 0, time.UTC).Day(); date.Day < 1 || date.Day > lastDay {
+			return fmt.Errorf("invalid vest date: %s %d", date.Month, date.Day)
+		}
+	}
+	return nil
+}
+
+// validateFixedVestDates ensures the employee's company vest dates are
+// valid, and that an employee whose schedules vest on fixed dates has some
+func validateFixedVestDates(employee Employee) error {
+	if len(employee.VestDates) > 0 {
+		return validateVestDates(employee.VestDates)
+	}
+	fixed := employee.Schedule.VestDay == "fixed"
+	for _, amendment := range employee.Amendments {
+		fixed = fixed || amendment.Schedule.VestDay == "fixed"
+	}
+	if employee.Exchange != nil && employee.Exchange.ResetSchedule != nil {
+		fixed = fixed || employee.Exchange.ResetSchedule.VestDay == "fixed"
+	}
+	if fixed {
+		return fmt.Errorf("employee %s vests on fixed dates but has no vest dates", employee.ID)
+	}
+	return nil
+}
+
+// nextBoundary returns the nth monthly boundary after start given the
+// (n-1)th, previous. Anniversary boundaries fall on start's day of the
+// month, or the last day of months too short for it, so a grant starting on
+// Jan 31 or Feb 29 vests at the end of February in every year. Fixed vest
+// dates accrue on anniversary boundaries too, see vestDate. Otherwise each
+// boundary is one month after the previous one as time.AddDate counts it,
+// which carries a start on the 31st over into the following month.
+func nextBoundary(start, previous time.Time, n int, vestDay string) time.Time {
+	if vestDay == "anniversary" || vestDay == "fixed" {
+		return anniversary(start, n)
+	}
+	return previous.AddDate(0, 1, 0)
+}
+
+// anniversary returns the date n months after start on start's day of the
+// month, clamped to the last day of the month
+func anniversary(start time.Time, n int) time.Time {
+	year, month, day := start.Date()
+	hour, minute, second := start.Clock()
+
+	// Day 0 of the following month is the last day of this one
+	target := time.Date(year, month+time.Month(n), 1, 0, 0, 0, 0, start.Location())
+	lastDay := time.Date(target.Year(), target.Month()+1, 0, 0, 0, 0, 0, start.Location()).Day()
+	return time.Date(target.Year(), target.Month(), min(day, lastDay), hour, minute, second, start.Nanosecond(), start.Location())
+}
+
+// vestDate returns the date units vest for a monthly boundary of the
+// employee's schedule. Schedules with fixed vest days hold the units
+// accrued at the boundary until the first company vest date on or after
+// it, and a business-day convention then moves the date off weekends and
+// holidays on the employee's calendar.
+func vestDate(employee Employee, boundary time.Time) time.Time {
+	if employee.Schedule.VestDay == "fixed" {
+		boundary = nextFixedDate(employee.VestDates, boundary)
+	}
+	return employee.Calendar.Adjust(boundary, employee.Schedule.BusinessDays)
+}
+
+// nextFixedDate returns the first of dates on or after t's date, at t's
+// time of day. Without dates t is returned as it is.
+func nextFixedDate(dates []MonthDay, t time.Time) time.Time {
+	hour, minute, second := t.Clock()
+
+	var next time.Time
+	for _, year := range []int{t.Year(), t.Year() + 1} {
+		for _, date := range dates {
+			lastDay := time.Date(year, date.Month+1, 0, 0, 0, 0, 0, t.Location()).Day()
+			candidate := time.Date(year, date.Month, min(date.Day, lastDay), hour, minute, second, t.Nanosecond(), t.Location())
+			if !candidate.Before(t) && (next.IsZero() || candidate.Before(next)) {
+				next = candidate
+			}
+		}
+	}
+	if next.IsZero() {
+		return t
+	}
+	return next
+}
+
+// countMonths returns the months of the employee's schedule started by end,
+// which is monthsBetween counted with the schedule's vest-day rule and
+// business-day convention
+func countMonths(employee Employee, end time.Time) int {
+	vestDay := employee.Schedule.VestDay
+	if vestDay == "" && employee.Schedule.BusinessDays == "" {
+		return monthsBetween(employee.StartDate, end)
+	}
+
+	months := 0
+	boundary := employee.StartDate
+	for vestDate(employee, boundary).Before(end) {
+		months++
+		boundary = nextBoundary(employee.StartDate, boundary, months, vestDay)
+	}
+	return months
+}
+
+// nextVestOn returns the date of the first vest event after monthsEmployed
+// months, which is the vest date of the boundary the month that changes the
+// vested units starts from. It is zero when nothing else vests.
+func nextVestOn(employee Employee, monthsEmployed int) time.Time {
+	current := vestedForMonths(employee, monthsEmployed, nil)
+	boundary := employee.StartDate
+	for months := 1; months <= scheduleHorizon(employee.Schedule); months++ {
+		if months > monthsEmployed && vestedForMonths(employee, months, nil) != current {
+			return vestDate(employee, boundary)
+		}
+		boundary = nextBoundary(employee.StartDate, boundary, months, employee.Schedule.VestDay)
+	}
+	return time.Time{}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnniversary(t *testing.T) {
+	tests := []struct {
+		start    time.Time
+		months   int
+		expected time.Time
+	}{
+		{date(2023, 1, 31), 1, date(2023, 2, 28)},
+		{date(2023, 1, 31), 2, date(2023, 3, 31)},
+		{date(2023, 1, 31), 3, date(2023, 4, 30)},
+		{date(2024, 1, 31), 1, date(2024, 2, 29)},
+		{date(2023, 1, 31), 11, date(2023, 12, 31)},
+		{date(2023, 1, 31), 13, date(2024, 2, 29)},
+		{date(2024, 2, 29), 1, date(2024, 3, 29)},
+		{date(2024, 2, 29), 12, date(2025, 2, 28)},
+		{date(2024, 2, 29), 48, date(2028, 2, 29)},
+		{date(2023, 8, 30), 6, date(2024, 2, 29)},
+		{date(2023, 3, 15), 0, date(2023, 3, 15)},
+	}
+
+	for _, tt := range tests {
+		if got := anniversary(tt.start, tt.months); !got.Equal(tt.expected) {
+			t.Errorf("anniversary(%s, %d) = %s, want %s", tt.start.Format("2006-01-02"), tt.months,
+				got.Format("2006-01-02"), tt.expected.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestAnniversaryVesting(t *testing.T) {
+	service := NewVestingService()
+	schedule := VestingSchedule{CliffMonths: 0, VestingMonths: 12, VestingType: "linear", VestDay: "anniversary"}
+	employee := Employee{ID: "emp001", StartDate: date(2023, 1, 31), TotalUnits: 1200, Schedule: schedule}
+
+	// Months are counted on the 31st or the last day of the month
+	for _, tt := range []struct {
+		end    time.Time
+		months int
+	}{
+		{date(2023, 1, 31), 0},
+		{date(2023, 2, 28), 1},
+		{date(2023, 3, 1), 2},
+		{date(2023, 3, 31), 2},
+		{date(2023, 4, 1), 3},
+	} {
+		if got := countMonths(employee, tt.end); got != tt.months {
+			t.Errorf("countMonths(%s) = %d, want %d", tt.end.Format("2006-01-02"), got, tt.months)
+		}
+	}
+
+	expected := []time.Time{
+		date(2023, 1, 31), date(2023, 2, 28), date(2023, 3, 31), date(2023, 4, 30),
+		date(2023, 5, 31), date(2023, 6, 30), date(2023, 7, 31), date(2023, 8, 31),
+		date(2023, 9, 30), date(2023, 10, 31), date(2023, 11, 30), date(2023, 12, 31),
+	}
+	events := vestEvents(employee)
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d", len(expected), len(events))
+	}
+	for i, event := range events {
+		if !event.Date.Equal(expected[i]) || event.Units != 100 {
+			t.Errorf("Event %d: expected 100 units on %s, got %d on %s", i+1,
+				expected[i].Format("2006-01-02"), event.Units, event.Date.Format("2006-01-02"))
+		}
+	}
+
+	// The next vest date is the next event's date
+	for _, tt := range []struct {
+		asOfDate     time.Time
+		cliffMonths  int
+		nextVestDate time.Time
+	}{
+		{date(2023, 2, 10), 0, date(2023, 2, 28)},
+		{date(2023, 2, 28), 0, date(2023, 2, 28)},
+		{date(2023, 3, 1), 0, date(2023, 3, 31)},
+		// Linear units first vest in the month after the cliff
+		{date(2023, 2, 10), 3, date(2023, 4, 30)},
+	} {
+		employee := employee
+		employee.Schedule.CliffMonths = tt.cliffMonths
+		result, err := service.calculateVesting(employee, tt.asOfDate)
+		if err != nil {
+			t.Fatalf("calculateVesting failed: %v", err)
+		}
+		if !result.NextVestDate.Equal(tt.nextVestDate) {
+			t.Errorf("As of %s with a %d month cliff: expected next vest %s, got %s", tt.asOfDate.Format("2006-01-02"),
+				tt.cliffMonths, tt.nextVestDate.Format("2006-01-02"), result.NextVestDate.Format("2006-01-02"))
+		}
+		for _, event := range vestEvents(employee) {
+			if !event.Date.Before(tt.asOfDate) {
+				if !event.Date.Equal(result.NextVestDate) {
+					t.Errorf("Next vest date %s is not the next event's, %s",
+						result.NextVestDate.Format("2006-01-02"), event.Date.Format("2006-01-02"))
+				}
+				break
+			}
+		}
+	}
+}
+
+func TestAnniversaryConsistency(t *testing.T) {
+	service := NewVestingService()
+
+	for _, start := range []time.Time{date(2023, 1, 31), date(2024, 2, 29), date(2023, 5, 30)} {
+		employee := Employee{
+			ID:         "emp001",
+			StartDate:  start,
+			TotalUnits: 4800,
+			Schedule:   VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear", VestDay: "anniversary"},
+		}
+
+		events := vestEvents(employee)
+		points, err := service.Timeline(employee, start, start.AddDate(4, 2, 0), DailyStep)
+		if err != nil {
+			t.Fatalf("Timeline failed: %v", err)
+		}
+		for _, point := range points {
+			expected, _ := service.calculateVesting(employee, point.Date)
+			if point.VestedUnits != expected.VestedUnits {
+				t.Fatalf("Start %s: timeline at %s has %d vested units, calculateVesting %d", start.Format("2006-01-02"),
+					point.Date.Format("2006-01-02"), point.VestedUnits, expected.VestedUnits)
+			}
+
+			sum := 0
+			for _, event := range eventsBetween(events, start, point.Date) {
+				sum += event.Units
+			}
+			if sum != expected.VestedUnits {
+				t.Fatalf("Start %s: events before %s sum to %d, calculateVesting %d", start.Format("2006-01-02"),
+					point.Date.Format("2006-01-02"), sum, expected.VestedUnits)
+			}
+		}
+
+		// Every vest falls on the start's day or the last day of the month
+		for _, event := range events {
+			lastDay := event.Date.AddDate(0, 0, 1).Day() == 1
+			if event.Date.Day() != start.Day() && !lastDay {
+				t.Errorf("Start %s: vest on %s drifted", start.Format("2006-01-02"), event.Date.Format("2006-01-02"))
+			}
+		}
+	}
+
+	if err := ValidateSchedule(VestingSchedule{CliffMonths: 0, VestingMonths: 12, VestingType: "linear", VestDay: "last"}); err == nil {
+		t.Error("Expected an unknown vest day to be rejected")
+	}
+}
+
+func TestNextFixedDate(t *testing.T) {
+	quarterly := []MonthDay{{time.February, 15}, {time.May, 15}, {time.August, 15}, {time.November, 15}}
+
+	tests := []struct {
+		dates    []MonthDay
+		date     time.Time
+		expected time.Time
+	}{
+		{quarterly, date(2024, 1, 10), date(2024, 2, 15)},
+		{quarterly, date(2024, 2, 15), date(2024, 2, 15)},
+		{quarterly, date(2024, 2, 16), date(2024, 5, 15)},
+		{quarterly, date(2024, 11, 16), date(2025, 2, 15)},
+		{[]MonthDay{{time.February, 29}}, date(2025, 1, 1), date(2025, 2, 28)},
+		{[]MonthDay{{time.February, 29}}, date(2024, 2, 29), date(2024, 2, 29)},
+		{nil, date(2024, 1, 10), date(2024, 1, 10)},
+	}
+
+	for _, tt := range tests {
+		if got := nextFixedDate(tt.dates, tt.date); !got.Equal(tt.expected) {
+			t.Errorf("nextFixedDate(%v, %s) = %s, want %s", tt.dates, tt.date.Format("2006-01-02"),
+				got.Format("2006-01-02"), tt.expected.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestFixedVestDates(t *testing.T) {
+	service := NewVestingService()
+	quarterly := []MonthDay{{time.February, 15}, {time.May, 15}, {time.August, 15}, {time.November, 15}}
+
+	type vest struct {
+		date  time.Time
+		units int
+	}
+	tests := []struct {
+		name      string
+		startDate time.Time
+		schedule  VestingSchedule
+		vests     []vest
+	}{
+		{
+			// The one-year cliff passes on 2025-01-10 and the units accrued
+			// by Feb 10 vest together on Feb 15
+			name:      "cliff_between_dates",
+			startDate: date(2024, 1, 10),
+			schedule:  VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear", VestDay: "fixed"},
+			vests: []vest{
+				{date(2025, 2, 15), 200}, {date(2025, 5, 15), 300}, {date(2025, 8, 15), 300}, {date(2025, 11, 15), 300},
+				{date(2026, 2, 15), 300}, {date(2026, 5, 15), 300}, {date(2026, 8, 15), 300}, {date(2026, 11, 15), 300},
+				{date(2027, 2, 15), 300}, {date(2027, 5, 15), 300}, {date(2027, 8, 15), 300}, {date(2027, 11, 15), 300},
+				{date(2028, 2, 15), 100},
+			},
+		},
+		{
+			// Hired the day after a vest date, so the first vest waits a quarter
+			name:      "hired_after_date",
+			startDate: date(2024, 2, 16),
+			schedule:  VestingSchedule{CliffMonths: 0, VestingMonths: 12, VestingType: "linear", VestDay: "fixed"},
+			vests: []vest{
+				{date(2024, 5, 15), 900}, {date(2024, 8, 15), 900}, {date(2024, 11, 15), 900}, {date(2025, 2, 15), 900},
+			},
+		},
+		{
+			// Hired on a vest date, which is on or after the first month's accrual
+			name:      "hired_on_date",
+			startDate: date(2024, 2, 15),
+			schedule:  VestingSchedule{CliffMonths: 0, VestingMonths: 12, VestingType: "linear", VestDay: "fixed"},
+			vests: []vest{
+				{date(2024, 2, 15), 300}, {date(2024, 5, 15), 900}, {date(2024, 8, 15), 900},
+				{date(2024, 11, 15), 900}, {date(2025, 2, 15), 600},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			employee := Employee{ID: "emp001", StartDate: tt.startDate, TotalUnits: 3600, Schedule: tt.schedule, VestDates: quarterly}
+
+			events := vestEvents(employee)
+			if len(events) != len(tt.vests) {
+				t.Fatalf("Expected %d vests, got %+v", len(tt.vests), events)
+			}
+			for i, event := range events {
+				if !event.Date.Equal(tt.vests[i].date) || event.Units != tt.vests[i].units {
+					t.Errorf("Vest %d: expected %d units on %s, got %d on %s", i+1, tt.vests[i].units,
+						tt.vests[i].date.Format("2006-01-02"), event.Units, event.Date.Format("2006-01-02"))
+				}
+			}
+			if first := events[0]; first.Cliff != (tt.schedule.CliffMonths > 0) || !events[len(events)-1].Final {
+				t.Errorf("Expected the cliff and final flags on the first and last vests, got %+v", events)
+			}
+
+			// Timeline, calculateVesting and the events agree every day
+			points, err := service.Timeline(employee, tt.startDate, tt.startDate.AddDate(4, 3, 0), DailyStep)
+			if err != nil {
+				t.Fatalf("Timeline failed: %v", err)
+			}
+			next := 0
+			for _, point := range points {
+				expected, err := service.calculateVesting(employee, point.Date)
+				if err != nil {
+					t.Fatalf("calculateVesting failed: %v", err)
+				}
+				for next < len(events) && events[next].Date.Before(point.Date) {
+					next++
+				}
+				vested := 0
+				if next > 0 {
+					vested = events[next-1].CumulativeUnits
+				}
+				if point.VestedUnits != expected.VestedUnits || vested != expected.VestedUnits {
+					t.Fatalf("At %s: timeline has %d vested units, events %d, calculateVesting %d",
+						point.Date.Format("2006-01-02"), point.VestedUnits, vested, expected.VestedUnits)
+				}
+				if next < len(events) && !expected.NextVestDate.Equal(events[next].Date) {
+					t.Fatalf("At %s: expected next vest %s, got %s", point.Date.Format("2006-01-02"),
+						events[next].Date.Format("2006-01-02"), expected.NextVestDate.Format("2006-01-02"))
+				}
+			}
+		})
+	}
+}
+
+func TestFixedVestDatesTermination(t *testing.T) {
+	service := NewVestingService()
+	quarterly := []MonthDay{{time.February, 15}, {time.May, 15}, {time.August, 15}, {time.November, 15}}
+	schedule := VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear", VestDay: "fixed"}
+
+	// Units accrued after Feb 15 are forfeited when the employee leaves
+	// before May 15
+	employee := Employee{ID: "emp001", StartDate: date(2024, 1, 10), TotalUnits: 3600, Schedule: schedule,
+		VestDates: quarterly, TerminationDate: date(2025, 4, 1)}
+	result, err := service.calculateVesting(employee, date(2025, 6, 1))
+	if err != nil {
+		t.Fatalf("calculateVesting failed: %v", err)
+	}
+	if result.VestedUnits != 200 || !result.NextVestDate.IsZero() {
+		t.Errorf("Expected 200 units and no next vest, got %d and %s", result.VestedUnits, result.NextVestDate.Format("2006-01-02"))
+	}
+
+	// Employees without vest dates use the tenant's
+	if err := service.RegisterTenant("acme", TenantSettings{VestDates: quarterly}); err != nil {
+		t.Fatalf("RegisterTenant failed: %v", err)
+	}
+	employee = Employee{ID: "emp002", TenantID: "acme", StartDate: date(2024, 1, 10), TotalUnits: 3600, Schedule: schedule}
+	if result, err := service.calculateVesting(employee, date(2025, 2, 16)); err != nil || result.VestedUnits != 200 {
+		t.Errorf("Expected 200 units on the tenant's vest dates, got %d (%v)", result.VestedUnits, err)
+	}
+
+	employee.TenantID = ""
+	if _, err := service.calculateVesting(employee, date(2025, 2, 16)); err == nil {
+		t.Error("Expected a fixed schedule without vest dates to be rejected")
+	}
+	if err := service.RegisterTenant("globex", TenantSettings{VestDates: []MonthDay{{time.February, 30}}}); err == nil {
+		t.Error("Expected Feb 30 to be rejected")
+	}
+	if err := service.RegisterTenant("initech", TenantSettings{VestDates: []MonthDay{{13, 1}}}); err == nil {
+		t.Error("Expected month 13 to be rejected")
+	}
+}
@@ -2,45 +2,96 @@ package main
 
 import (
 	"fmt"
-	"math"
+	"log/slog"
+	"math/big"
 	"sync"
 	"time"
 )
 
 type VestingService struct {
-	cache *VestingCache
-	mu    sync.Mutex
+	cache    *VestingCache
+	tenants  map[string]TenantSettings
+	splits   map[string][]StockSplit
+	webhooks *WebhookDispatcher
+	metrics  *serviceMetrics
+	logger   *slog.Logger
+	logNames bool
+	now      func() time.Time
+	mu       sync.Mutex
 }
 
 func NewVestingService() *VestingService {
 	return &VestingService{
-		cache: NewVestingCache(),
+		cache:   NewVestingCache(),
+		tenants: make(map[string]TenantSettings),
+		splits:  make(map[string][]StockSplit),
+		metrics: newServiceMetrics(),
+		logger:  slog.Default(),
+		now:     time.Now,
 	}
 }
 
-// ProcessBatch calculates vesting for multiple employees concurrently
+// ProcessBatch calculates vesting for multiple employees of the default
+// tenant concurrently
 func (vs *VestingService) ProcessBatch(employees []Employee, asOfDate time.Time) error {
+	return vs.processBatch(defaultTenant, employees, asOfDate)
+}
+
+// processBatch calculates vesting for multiple employees of a tenant
+// concurrently
+func (vs *VestingService) processBatch(tenantID string, employees []Employee, asOfDate time.Time) error {
+	start := time.Now()
+	defer func() { vs.metrics.observeBatch(time.Since(start)) }()
+
+	logger := vs.log().With(slog.String("tenant_id", tenantID))
+	logger.Info("batch started",
+		slog.Int("employees", len(employees)),
+		slog.Time("as_of", asOfDate))
+
 	var wg sync.WaitGroup
 	errors := make(chan error, len(employees))
+	notifications := newWebhookBatch(vs.webhookDispatcher())
 
 	for _, emp := range employees {
 		wg.Add(1)
 		go func(employee Employee) {
 			defer wg.Done()
-			result, err := vs.calculateVesting(employee, asOfDate)
+			vs.metrics.inFlight.Add(1)
+			defer vs.metrics.inFlight.Add(-1)
+
+			employee, err := tenantEmployee(tenantID, employee)
+			if err != nil {
+				vs.employeeFailed(employee, errorTypeTenant, err)
+				errors <- err
+				return
+			}
+
+			employee = vs.applyTenantSettings(employee)
+			result, err := vs.timedCalculation(employee, asOfDate)
 			if err != nil {
+				vs.employeeFailed(employee, errorTypeValidation, err)
 				errors <- err
 				return
 			}
 
 			// Store result in cache
-			vs.cache.results[employee.ID] = result
+			vs.storeResult(employee, result)
+			notifications.observe(employee, result)
 		}(emp)
 	}
 
 	wg.Wait()
 	close(errors)
 
+	// Results that were stored are reported even if others failed. Delivery
+	// happens on the dispatcher's goroutine, not the batch's.
+	notifications.dispatch()
+
+	logger.Info("batch finished",
+		slog.Int("employees", len(employees)),
+		slog.Int("failed", len(errors)),
+		slog.Duration("duration", time.Since(start)))
+
 	// Check for any errors
 	for err := range errors {
 		if err != nil {
@@ -51,94 +102,275 @@ func (vs *VestingService) ProcessBatch(employees []Employee, asOfDate time.Time)
 	return nil
 }
 
+// employeeFailed records an employee whose calculation was rejected
+func (vs *VestingService) employeeFailed(employee Employee, errorType string, err error) {
+	vs.metrics.countError(errorType)
+	vs.log().Warn("employee rejected",
+		vs.employeeAttr(employee),
+		slog.String("error_type", errorType),
+		slog.String("error", err.Error()))
+}
+
 // calculateVesting calculates vested units for a single employee
 func (vs *VestingService) calculateVesting(employee Employee, asOfDate time.Time) (VestingResult, error) {
+	return vs.calculate(employee, asOfDate, time.Time{}, nil)
+}
+
+// timedCalculation is calculateVesting recorded in the calculation latency
+// histogram
+func (vs *VestingService) timedCalculation(employee Employee, asOfDate time.Time) (VestingResult, error) {
+	start := time.Now()
+	defer func() { vs.metrics.observeCalculation(time.Since(start)) }()
+
+	return vs.calculateVesting(employee, asOfDate)
+}
+
+// calculate does the work of calculateVesting, recording each step in
+// explain when it is non-nil. Stock splits recorded after knownAt are
+// ignored unless it is zero.
+func (vs *VestingService) calculate(employee Employee, asOfDate, knownAt time.Time, explain *VestingExplanation) (VestingResult, error) {
 	if employee.TotalUnits <= 0 {
 		return VestingResult{}, fmt.Errorf("invalid total units: %d", employee.TotalUnits)
 	}
 
-	monthsEmployed := monthsBetween(employee.StartDate, asOfDate)
+	if err := validateTermChanges(employee); err != nil {
+		return VestingResult{}, err
+	}
+	if err := validateOptionTerms(employee); err != nil {
+		return VestingResult{}, err
+	}
+	if err := validateGrantDates(employee); err != nil {
+		return VestingResult{}, err
+	}
+
+	employee = vs.applyTenantSettings(employee)
+	asOfDate = vs.tenantTime(employee.TenantID, asOfDate)
+	if err := validateFixedVestDates(employee); err != nil {
+		return VestingResult{}, err
+	}
+
+	// After an exchange the replacement grant is calculated, its units
+	// converted from the old grant's when it kept the old vesting
+	grant, conversion := grantAt(employee, asOfDate)
+
+	// Amended grants are calculated under the schedule in force, counting
+	// months from the amendment and adding the units vested before it
+	cutoff := vestingCutoff(employee, asOfDate)
+	segment := activeSegment(vestingSegments(grant), cutoff)
+	terms := segment.employee
+	monthsEmployed := countMonths(terms, cutoff)
+
+	if explain != nil {
+		*explain = VestingExplanation{
+			EmployeeID:     employee.ID,
+			StartDate:      grant.StartDate,
+			AsOfDate:       asOfDate,
+			TotalUnits:     grant.TotalUnits,
+			VestingType:    terms.Schedule.VestingType,
+			CliffMonths:    terms.Schedule.CliffMonths,
+			VestingMonths:  terms.Schedule.VestingMonths,
+			MonthsEmployed: monthsEmployed,
+			CliffReached:   monthsEmployed >= terms.Schedule.CliffMonths,
+			Rounding:       roundingName(terms.Schedule.Rounding),
+			Remainder:      remainderName(terms.Schedule),
+		}
+		if commencement := commencementDate(grant); !commencement.Equal(grant.StartDate) {
+			explain.CommencementDate = commencement
+		}
+		if segment.amended {
+			explain.AmendedOn = segment.from
+			explain.UnitsBeforeAmendment = segment.baseUnits
+		}
+		if exchangedBy(employee, asOfDate) {
+			explain.ExchangedOn = employee.Exchange.EffectiveDate
+			explain.ConversionRatio = fmt.Sprintf("%d:%d", employee.Exchange.Numerator, employee.Exchange.Denominator)
+		}
+	}
+
+	vestedUnits := segment.baseUnits + vestedForMonths(terms, monthsEmployed, explain)
 
 	// Check if still in cliff period
+	var nextVestDate time.Time
+	if terminatedBy(employee, asOfDate) {
+		// Nothing else vests after termination
+	} else if monthsEmployed < terms.Schedule.CliffMonths {
+		nextVestDate = addMonths(terms.StartDate, terms.Schedule.CliffMonths)
+	} else if vestedUnits < grant.TotalUnits {
+		// Linear and backloaded both vest again at the next month
+		nextVestDate = addMonths(asOfDate, 1)
+	}
+	if !nextVestDate.IsZero() && (terms.Schedule.VestDay != "" || terms.Schedule.BusinessDays != "") {
+		// Schedules with a vest-day rule or business-day convention report
+		// the date of the next vest event
+		nextVestDate = nextVestOn(terms, monthsEmployed)
+	}
+
+	// Units vest on the grant's basis and are restated after any split
+	basis := vs.splitBasis(employee, asOfDate, knownAt)
+	totalUnits := basis.Units(conversion.Units(grant.TotalUnits))
+	vestedUnits = basis.Units(conversion.Units(vestedUnits))
+
+	// Vested options can no longer be exercised once the grant expires
+	exercisableUnits, expiredUnits := vestedUnits, 0
+	expiration, _ := expirationDate(grant)
+	if !expiration.IsZero() && !asOfDate.Before(expiration) {
+		exercisableUnits, expiredUnits = 0, vestedUnits
+	}
+
+	result := VestingResult{
+		TenantID:         employee.TenantID,
+		EmployeeID:       employee.ID,
+		VestedUnits:      vestedUnits,
+		UnvestedUnits:    totalUnits - vestedUnits,
+		NextVestDate:     nextVestDate,
+		AsOfDate:         asOfDate,
+		HireDate:         employee.StartDate,
+		CommencementDate: commencementDate(grant),
+		StrikePrice:      basis.Price(strikeAt(grant, asOfDate)),
+		Basis:            basis,
+		Rounding:         roundingName(terms.Schedule.Rounding),
+		Remainder:        remainderName(terms.Schedule),
+
+		ExercisableUnits: exercisableUnits,
+		ExpiredUnits:     expiredUnits,
+		ExpirationDate:   expiration,
+	}
+	explain.finish(result)
+	return result, nil
+}
+
+// vestedForMonths returns the units vested after monthsEmployed months of
+// the employee's schedule, recording the steps in explain when it is non-nil
+func vestedForMonths(employee Employee, monthsEmployed int, explain *VestingExplanation) int {
+	// Nothing vests during the cliff period
 	if monthsEmployed < employee.Schedule.CliffMonths {
-		return VestingResult{
-			EmployeeID:    employee.ID,
-			VestedUnits:   0,
-			UnvestedUnits: employee.TotalUnits,
-			NextVestDate:  addMonths(employee.StartDate, employee.Schedule.CliffMonths),
-			AsOfDate:      asOfDate,
-		}, nil
+		return 0
+	}
+
+	// The vested fraction is kept exact and only rounded once, to units
+	fraction := vestedFraction(employee, monthsEmployed, explain)
+	if fraction == nil {
+		return 0
+	}
+	units := unitsOf(employee.TotalUnits, fraction)
+	if explain != nil {
+		explain.setFraction(fraction, units)
+	}
+
+	if employee.Schedule.Rounding == "tranche-floor" {
+		return trancheFloorUnits(employee, monthsEmployed)
 	}
+	return roundUnits(units, employee.Schedule.Rounding)
+}
 
-	var vestedUnits int
+// vestedFraction returns the exact fraction of the grant vested after
+// monthsEmployed months, or nil for an unknown vesting type
+func vestedFraction(employee Employee, monthsEmployed int, explain *VestingExplanation) *big.Rat {
+	if monthsEmployed < employee.Schedule.CliffMonths {
+		return new(big.Rat)
+	}
 
 	if employee.Schedule.VestingType == "linear" {
 		// Linear vesting: equal amounts each month after cliff
 		monthsVested := monthsEmployed - employee.Schedule.CliffMonths
-		if monthsVested > employee.Schedule.VestingMonths - employee.Schedule.CliffMonths {
+		if monthsVested > employee.Schedule.VestingMonths-employee.Schedule.CliffMonths {
 			monthsVested = employee.Schedule.VestingMonths - employee.Schedule.CliffMonths
 		}
 
 		vestingMonthsAfterCliff := employee.Schedule.VestingMonths - employee.Schedule.CliffMonths
-		unitsPerMonth := float64(employee.TotalUnits) / float64(vestingMonthsAfterCliff)
-		vestedUnits = int(unitsPerMonth * float64(monthsVested))
+		fraction := big.NewRat(1, 1)
+		if vestingMonthsAfterCliff > 0 {
+			fraction.SetFrac64(int64(monthsVested), int64(vestingMonthsAfterCliff))
+		}
+
+		if explain != nil {
+			explain.MonthsVested = monthsVested
+			if vestingMonthsAfterCliff > 0 {
+				explain.UnitsPerMonth = ratFloat(big.NewRat(int64(employee.TotalUnits), int64(vestingMonthsAfterCliff)))
+			}
+		}
+		return fraction
 
 	} else if employee.Schedule.VestingType == "backloaded" {
 		// Backloaded vesting: 10% year 1, 20% year 2, 30% year 3, 40% year 4
 		yearsVested := (monthsEmployed - employee.Schedule.CliffMonths) / 12
 
-		percentages := []float64{0.1, 0.2, 0.3, 0.4}
-		totalPercent := 0.0
+		percentages := backloadedPercentages
+		totalPercent := new(big.Rat)
 
 		for i := 0; i <= yearsVested && i < len(percentages); i++ {
-			totalPercent += percentages[i]
+			totalPercent.Add(totalPercent, percentages[i])
+			explain.addYear(i+1, percentages[i], 12, percentages[i])
 		}
 
 		// Add partial year vesting for current year
 		monthsInCurrentYear := (monthsEmployed - employee.Schedule.CliffMonths) % 12
 		if yearsVested < len(percentages) && monthsInCurrentYear > 0 {
-			currentYearPercent := percentages[yearsVested] * (float64(monthsInCurrentYear) / 12.0)
-			totalPercent += currentYearPercent
+			currentYearPercent := new(big.Rat).Mul(percentages[yearsVested], big.NewRat(int64(monthsInCurrentYear), 12))
+			totalPercent.Add(totalPercent, currentYearPercent)
+			explain.addYear(yearsVested+1, percentages[yearsVested], monthsInCurrentYear, currentYearPercent)
 		}
 
-		vestedUnits = int(math.Floor(float64(employee.TotalUnits) * totalPercent))
-	}
+		// Years are counted from the cliff, so the last year's partial
+		// vesting can run past the whole grant
+		if totalPercent.Cmp(big.NewRat(1, 1)) > 0 {
+			totalPercent.SetInt64(1)
+		}
 
-	// Calculate next vest date
-	var nextVestDate time.Time
-	if vestedUnits < employee.TotalUnits {
-		if employee.Schedule.VestingType == "linear" {
-			nextVestDate = addMonths(asOfDate, 1)
-		} else {
-			// For backloaded, next vest is at the next month
-			nextVestDate = addMonths(asOfDate, 1)
+		if explain != nil {
+			explain.MonthsVested = monthsEmployed - employee.Schedule.CliffMonths
 		}
+		return totalPercent
 	}
 
-	return VestingResult{
-		EmployeeID:    employee.ID,
-		VestedUnits:   vestedUnits,
-		UnvestedUnits: employee.TotalUnits - vestedUnits,
-		NextVestDate:  nextVestDate,
-		AsOfDate:      asOfDate,
-	}, nil
+	return nil
+}
+
+// ExplainVesting calculates vesting for a single employee and returns a
+// breakdown of how the result was derived alongside it
+func (vs *VestingService) ExplainVesting(employee Employee, asOfDate time.Time) (VestingResult, *VestingExplanation, error) {
+	explain := &VestingExplanation{}
+	result, err := vs.calculate(employee, asOfDate, time.Time{}, explain)
+	if err != nil {
+		return VestingResult{}, nil, err
+	}
+	return result, explain, nil
 }
 
-// GetResult retrieves a vesting result from cache
+// GetResult retrieves a vesting result of the default tenant from cache
 func (vs *VestingService) GetResult(employeeID string) (VestingResult, bool) {
+	return vs.getResult(defaultTenant, employeeID)
+}
+
+// getResult retrieves a vesting result of a tenant from cache
+func (vs *VestingService) getResult(tenantID, employeeID string) (VestingResult, bool) {
 	vs.mu.Lock()
 	defer vs.mu.Unlock()
 
-	result, exists := vs.cache.results[employeeID]
+	result, exists := vs.cache.results[cacheKey{TenantID: tenantID, EmployeeID: employeeID}]
+	vs.metrics.countLookup(exists)
 	return result, exists
 }
 
-// ClearCache clears all cached results
-func (vs *VestingService) ClearCache() {
+// storeResult writes a result and the employee it was calculated for to the
+// cache
+func (vs *VestingService) storeResult(employee Employee, result VestingResult) {
 	vs.mu.Lock()
 	defer vs.mu.Unlock()
 
+	key := cacheKey{TenantID: employee.TenantID, EmployeeID: employee.ID}
+	vs.cache.results[key] = result
+	vs.cache.employees[key] = employee
+}
+
+// ClearCache clears all cached results of every tenant
+func (vs *VestingService) ClearCache() {
+	vs.mu.Lock()
+	size := len(vs.cache.results)
 	vs.cache = NewVestingCache()
+	vs.mu.Unlock()
+
+	vs.log().Info("cache cleared", slog.Int("results", size))
 }
 
 // Helper functions
@@ -166,14 +398,35 @@ func addMonths(t time.Time, months int) time.Time {
 	return t.AddDate(0, months, 0)
 }
 
-// GetBatchResults returns all results for a list of employee IDs
+// terminatedBy reports whether the employee has left on or before date
+func terminatedBy(employee Employee, date time.Time) bool {
+	return !employee.TerminationDate.IsZero() && !date.Before(employee.TerminationDate)
+}
+
+// vestingCutoff returns the date vesting is measured to, which is the
+// termination date once the employee has left
+func vestingCutoff(employee Employee, asOfDate time.Time) time.Time {
+	if terminatedBy(employee, asOfDate) {
+		return employee.TerminationDate
+	}
+	return asOfDate
+}
+
+// GetBatchResults returns all results of the default tenant for a list of
+// employee IDs
 func (vs *VestingService) GetBatchResults(employeeIDs []string) (map[string]VestingResult, error) {
+	return vs.getBatchResults(defaultTenant, employeeIDs)
+}
+
+// getBatchResults returns all results of a tenant for a list of employee IDs
+func (vs *VestingService) getBatchResults(tenantID string, employeeIDs []string) (map[string]VestingResult, error) {
 	results := make(map[string]VestingResult)
 
 	for _, id := range employeeIDs {
-		if result, exists := vs.GetResult(id); exists {
+		if result, exists := vs.getResult(tenantID, id); exists {
 			results[id] = result
 		} else {
+			vs.metrics.countError(errorTypeNotFound)
 			return nil, fmt.Errorf("result not found for employee %s", id)
 		}
 	}
@@ -192,5 +445,17 @@ func ValidateSchedule(schedule VestingSchedule) error {
 	if schedule.VestingType != "linear" && schedule.VestingType != "backloaded" {
 		return fmt.Errorf("invalid vesting type: %s", schedule.VestingType)
 	}
+	if err := validateRounding(schedule.Rounding); err != nil {
+		return err
+	}
+	if err := validateRemainder(schedule); err != nil {
+		return err
+	}
+	if err := validateVestDay(schedule.VestDay); err != nil {
+		return err
+	}
+	if err := validateBusinessDays(schedule.BusinessDays); err != nil {
+		return err
+	}
 	return nil
 }
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Webhook event types
+const (
+	WebhookCliffCrossed = "cliff_crossed"
+	WebhookVest         = "vest"
+	WebhookFullyVested  = "fully_vested"
+)
+
+// WebhookEndpoint is a receiver of webhook events. Payloads are signed with
+// Secret, see VerifyWebhookSignature.
+type WebhookEndpoint struct {
+	URL    string
+	Secret string
+}
+
+// WebhookEvent is the JSON payload posted for a vesting transition between
+// two runs for the same employee
+type WebhookEvent struct {
+	ID                  string    `json:"id"`
+	Type                string    `json:"type"`
+	TenantID            string    `json:"tenant_id,omitempty"`
+	EmployeeID          string    `json:"employee_id"`
+	PreviousVestedUnits int       `json:"previous_vested_units"`
+	VestedUnits         int       `json:"vested_units"`
+	UnvestedUnits       int       `json:"unvested_units"`
+	PreviousAsOfDate    time.Time `json:"previous_as_of_date"`
+	AsOfDate            time.Time `json:"as_of_date"`
+}
+
+// DeliveryRecord is one delivery attempt of an event to an endpoint
+type DeliveryRecord struct {
+	EventID    string    `json:"event_id"`
+	EventType  string    `json:"event_type"`
+	EmployeeID string    `json:"employee_id"`
+	Endpoint   string    `json:"endpoint"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Delivered  bool      `json:"delivered"`
+	Time       time.Time `json:"time"`
+}
+
+// DeliveryLog stores delivery attempts
+type DeliveryLog interface {
+	Record(record DeliveryRecord) error
+}
+
+// FileDeliveryLog appends delivery records to a file as JSON lines
+type FileDeliveryLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileDeliveryLog(path string) *FileDeliveryLog {
+	return &FileDeliveryLog{path: path}
+}
+
+// Record appends a delivery record to the log file
+func (l *FileDeliveryLog) Record(record DeliveryRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Records reads every delivery record from the log file in the order they
+// were written
+func (l *FileDeliveryLog) Records() ([]DeliveryRecord, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []DeliveryRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record DeliveryRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("invalid delivery record: %w", err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// WebhookDispatcher detects vesting transitions between runs and posts them
+// to its endpoints. Events handed to Enqueue are delivered in order on the
+// dispatcher's own goroutine, which runs while there are events to deliver,
+// and deliveries that fail are written to Logger.
+type WebhookDispatcher struct {
+	Endpoints []WebhookEndpoint
+	Client    *http.Client
+	Log       DeliveryLog
+	Logger    *slog.Logger
+
+	// MaxAttempts is the number of tries per endpoint, and Backoff is the
+	// wait before the first retry, doubling on each retry after it
+	MaxAttempts int
+	Backoff     time.Duration
+
+	sleep    func(time.Duration)
+	mu       sync.Mutex
+	lastSeen map[cacheKey]webhookState
+
+	queueMu  sync.Mutex
+	queued   *sync.Cond
+	queue    [][]WebhookEvent
+	pending  int
+	draining bool
+}
+
+// webhookState is what the dispatcher remembers of an employee's last run
+type webhookState struct {
+	result       VestingResult
+	cliffReached bool
+}
+
+func NewWebhookDispatcher(log DeliveryLog, endpoints ...WebhookEndpoint) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		Endpoints:   endpoints,
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		Log:         log,
+		Logger:      slog.Default(),
+		MaxAttempts: 5,
+		Backoff:     time.Second,
+		sleep:       time.Sleep,
+		lastSeen:    make(map[cacheKey]webhookState),
+	}
+}
+
+// SetWebhookDispatcher sends transitions detected by ProcessBatch and
+// StreamBatch to the dispatcher, which delivers them in the background; use
+// its Flush to wait for them. Pass nil to stop sending webhooks.
+func (vs *VestingService) SetWebhookDispatcher(dispatcher *WebhookDispatcher) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	vs.webhooks = dispatcher
+}
+
+// webhookDispatcher returns the configured dispatcher, which may be nil
+func (vs *VestingService) webhookDispatcher() *WebhookDispatcher {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	return vs.webhooks
+}
+
+// webhookBatch collects the transitions observed by concurrent calculations
+// so they can be dispatched together once a batch finishes
+type webhookBatch struct {
+	dispatcher *WebhookDispatcher
+	mu         sync.Mutex
+	events     []WebhookEvent
+}
+
+// newWebhookBatch returns a batch that does nothing when dispatcher is nil
+func newWebhookBatch(dispatcher *WebhookDispatcher) *webhookBatch {
+	return &webhookBatch{dispatcher: dispatcher}
+}
+
+func (b *webhookBatch) observe(employee Employee, result VestingResult) {
+	if b.dispatcher == nil {
+		return
+	}
+	events := b.dispatcher.Observe(employee, result)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.events = append(b.events, events...)
+}
+
+// dispatch queues the collected events for delivery ordered by employee
+func (b *webhookBatch) dispatch() {
+	if b.dispatcher == nil || len(b.events) == 0 {
+		return
+	}
+	sort.SliceStable(b.events, func(i, j int) bool {
+		return b.events[i].EmployeeID < b.events[j].EmployeeID
+	})
+	b.dispatcher.Enqueue(b.events)
+}
+
+// Observe records a new result for the employee and returns the transitions
+// since the previous one. The first result seen for an employee only sets
+// what the dispatcher remembers, and results no later than the remembered
+// one, such as those of a backfill, are ignored.
+func (d *WebhookDispatcher) Observe(employee Employee, result VestingResult) []WebhookEvent {
+	// The cliff is that of the schedule in force, after any exchange or
+	// amendment
+	grant, _ := grantAt(employee, result.AsOfDate)
+	cutoff := vestingCutoff(employee, result.AsOfDate)
+	terms := activeSegment(vestingSegments(grant), cutoff).employee
+	current := webhookState{
+		result:       result,
+		cliffReached: countMonths(terms, cutoff) >= terms.Schedule.CliffMonths,
+	}
+
+	key := cacheKey{TenantID: employee.TenantID, EmployeeID: employee.ID}
+	d.mu.Lock()
+	if d.lastSeen == nil {
+		d.lastSeen = make(map[cacheKey]webhookState)
+	}
+	previous, seen := d.lastSeen[key]
+	if seen && !result.AsOfDate.After(previous.result.AsOfDate) {
+		d.mu.Unlock()
+		return nil
+	}
+	d.lastSeen[key] = current
+	d.mu.Unlock()
+
+	if !seen {
+		return nil
+	}
+
+	// A stock split between the two runs is not a vest
+	previous.result = restate(previous.result, result.Basis)
+
+	newEvent := func(eventType string) WebhookEvent {
+		return WebhookEvent{
+			ID:                  webhookEventID(eventType, employee, result.AsOfDate),
+			Type:                eventType,
+			TenantID:            employee.TenantID,
+			EmployeeID:          employee.ID,
+			PreviousVestedUnits: previous.result.VestedUnits,
+			VestedUnits:         result.VestedUnits,
+			UnvestedUnits:       result.UnvestedUnits,
+			PreviousAsOfDate:    previous.result.AsOfDate,
+			AsOfDate:            result.AsOfDate,
+		}
+	}
+
+	var events []WebhookEvent
+	if terms.Schedule.CliffMonths > 0 && !previous.cliffReached && current.cliffReached {
+		events = append(events, newEvent(WebhookCliffCrossed))
+	}
+	if result.VestedUnits > previous.result.VestedUnits {
+		events = append(events, newEvent(WebhookVest))
+	}
+	if previous.result.UnvestedUnits > 0 && result.UnvestedUnits == 0 {
+		events = append(events, newEvent(WebhookFullyVested))
+	}
+	return events
+}
+
+// restate converts a result's units to another split basis
+func restate(result VestingResult, basis SplitBasis) VestingResult {
+	from := result.Basis
+	if from.Numerator == 0 {
+		from = grantBasis
+	}
+	if basis.Numerator == 0 || from == basis {
+		return result
+	}
+
+	ratio := SplitBasis{
+		Numerator:   basis.Numerator * from.Denominator,
+		Denominator: basis.Denominator * from.Numerator,
+	}
+	totalUnits := ratio.Units(result.VestedUnits + result.UnvestedUnits)
+	result.VestedUnits = ratio.Units(result.VestedUnits)
+	result.UnvestedUnits = totalUnits - result.VestedUnits
+	result.Basis = basis
+	return result
+}
+
+// webhookEventID returns an ID that is the same every time the same
+// transition is detected, so receivers can drop duplicates
+func webhookEventID(eventType string, employee Employee, asOfDate time.Time) string {
+	id := fmt.Sprintf("%s:%s:%s", eventType, employee.ID, asOfDate.Format(time.RFC3339))
+	if employee.TenantID != "" {
+		id = employee.TenantID + ":" + id
+	}
+	return id
+}
+
+// Enqueue queues events for delivery and returns without waiting for them,
+// starting the dispatcher's goroutine if it is not running
+func (d *WebhookDispatcher) Enqueue(events []WebhookEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	d.queueMu.Lock()
+	defer d.queueMu.Unlock()
+
+	d.queue = append(d.queue, events)
+	d.pending++
+	if !d.draining {
+		d.draining = true
+		go d.drain()
+	}
+}
+
+// Flush waits until every queued event has been delivered or has failed
+func (d *WebhookDispatcher) Flush() {
+	d.queueMu.Lock()
+	defer d.queueMu.Unlock()
+
+	for d.pending > 0 {
+		d.waiter().Wait()
+	}
+}
+
+// drain delivers queued events until the queue is empty
+func (d *WebhookDispatcher) drain() {
+	for {
+		d.queueMu.Lock()
+		if len(d.queue) == 0 {
+			d.draining = false
+			d.queueMu.Unlock()
+			return
+		}
+		events := d.queue[0]
+		d.queue = d.queue[1:]
+		d.queueMu.Unlock()
+
+		if err := d.Dispatch(events); err != nil {
+			d.logger().Warn("webhook delivery failed",
+				slog.Int("events", len(events)),
+				slog.String("error", err.Error()))
+		}
+
+		d.queueMu.Lock()
+		d.pending--
+		d.waiter().Broadcast()
+		d.queueMu.Unlock()
+	}
+}
+
+// waiter returns the condition Flush waits on. queueMu must be held.
+func (d *WebhookDispatcher) waiter() *sync.Cond {
+	if d.queued == nil {
+		d.queued = sync.NewCond(&d.queueMu)
+	}
+	return d.queued
+}
+
+func (d *WebhookDispatcher) logger() *slog.Logger {
+	if d.Logger == nil {
+		return slog.Default()
+	}
+	return d.Logger
+}
+
+// Dispatch posts every event to every endpoint, retrying failed deliveries
+// with exponential backoff. Every attempt is written to the delivery log. The
+// returned error joins the deliveries that failed on their last attempt.
+func (d *WebhookDispatcher) Dispatch(events []WebhookEvent) error {
+	var errs []error
+	for _, event := range events {
+		body, err := json.Marshal(event)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, endpoint := range d.Endpoints {
+			if err := d.deliver(endpoint, event, body); err != nil {
+				errs = append(errs, fmt.Errorf("event %s to %s: %w", event.ID, endpoint.URL, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deliver posts one event to one endpoint until it succeeds, fails with a
+// non-retryable status or runs out of attempts
+func (d *WebhookDispatcher) deliver(endpoint WebhookEndpoint, event WebhookEvent, body []byte) error {
+	attempts := max(d.MaxAttempts, 1)
+	backoff := d.Backoff
+	sleep := d.sleep
+	if sleep == nil {
+		sleep = time.Sleep
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			sleep(backoff)
+			backoff *= 2
+		}
+
+		statusCode, err := d.post(endpoint, event, body)
+		record := DeliveryRecord{
+			EventID:    event.ID,
+			EventType:  event.Type,
+			EmployeeID: event.EmployeeID,
+			Endpoint:   endpoint.URL,
+			Attempt:    attempt,
+			StatusCode: statusCode,
+			Delivered:  err == nil,
+			Time:       time.Now().UTC(),
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+		if d.Log != nil {
+			if logErr := d.Log.Record(record); logErr != nil {
+				return fmt.Errorf("recording delivery: %w", logErr)
+			}
+		}
+
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		// Client errors other than rate limiting will not succeed on retry
+		if statusCode >= 400 && statusCode < 500 && statusCode != http.StatusTooManyRequests {
+			break
+		}
+	}
+	return lastErr
+}
+
+// post sends a signed request and returns the response status code
+func (d *WebhookDispatcher) post(endpoint WebhookEndpoint, event WebhookEvent, body []byte) (int, error) {
+	request, err := http.NewRequest(http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Webhook-ID", event.ID)
+	request.Header.Set("X-Webhook-Timestamp", timestamp)
+	request.Header.Set("X-Webhook-Signature", signWebhook(endpoint.Secret, timestamp, body))
+
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+	return response.StatusCode, nil
+}
+
+// signWebhook returns the signature header value for a payload
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature reports whether signature is the X-Webhook-Signature
+// header for body and the X-Webhook-Timestamp header timestamp
+func VerifyWebhookSignature(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(signWebhook(secret, timestamp, body)), []byte(signature))
+}
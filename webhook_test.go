@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver is an httptest receiver that fails the first failures
+// requests and records the events it accepts
+type webhookReceiver struct {
+	secret   string
+	failures int
+
+	mu       sync.Mutex
+	requests int
+	events   []WebhookEvent
+	badSigs  int
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests++
+	if r.requests <= r.failures {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	if !VerifyWebhookSignature(r.secret, req.Header.Get("X-Webhook-Timestamp"), body, req.Header.Get("X-Webhook-Signature")) {
+		r.badSigs++
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.events = append(r.events, event)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestWebhookTransitions(t *testing.T) {
+	receiver := &webhookReceiver{secret: "s3cret"}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	logPath := filepath.Join(t.TempDir(), "deliveries.jsonl")
+	deliveryLog := NewFileDeliveryLog(logPath)
+	dispatcher := NewWebhookDispatcher(deliveryLog, WebhookEndpoint{URL: server.URL, Secret: "s3cret"})
+	dispatcher.sleep = func(time.Duration) {}
+
+	service := NewVestingService()
+	service.SetWebhookDispatcher(dispatcher)
+
+	employee := Employee{
+		ID:         "hook1",
+		StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		TotalUnits: 36000,
+		Schedule:   VestingSchedule{CliffMonths: 12, VestingMonths: 36, VestingType: "linear"},
+	}
+
+	runs := []struct {
+		asOfDate time.Time
+		expected []string
+	}{
+		// The first run only establishes a baseline
+		{asOfDate: time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{asOfDate: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), expected: []string{WebhookCliffCrossed}},
+		{asOfDate: time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC), expected: []string{WebhookVest}},
+		{asOfDate: time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{asOfDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), expected: []string{WebhookVest, WebhookFullyVested}},
+	}
+
+	for _, run := range runs {
+		before := len(receiver.events)
+		if err := service.ProcessBatch([]Employee{employee}, run.asOfDate); err != nil {
+			t.Fatalf("ProcessBatch failed: %v", err)
+		}
+		dispatcher.Flush()
+
+		var types []string
+		for _, event := range receiver.events[before:] {
+			types = append(types, event.Type)
+		}
+		if !slices.Equal(types, run.expected) {
+			t.Errorf("%s: expected events %v, got %v", run.asOfDate.Format("2006-01-02"), run.expected, types)
+		}
+	}
+
+	if receiver.badSigs != 0 {
+		t.Errorf("Receiver rejected %d signatures", receiver.badSigs)
+	}
+
+	records, err := deliveryLog.Records()
+	if err != nil {
+		t.Fatalf("Reading delivery log failed: %v", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("Expected 4 delivery records, got %d", len(records))
+	}
+	for _, record := range records {
+		if !record.Delivered || record.Attempt != 1 || record.StatusCode != http.StatusNoContent {
+			t.Errorf("Unexpected delivery record %+v", record)
+		}
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	receiver := &webhookReceiver{secret: "s3cret", failures: 2}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	deliveryLog := NewFileDeliveryLog(filepath.Join(t.TempDir(), "deliveries.jsonl"))
+	dispatcher := NewWebhookDispatcher(deliveryLog, WebhookEndpoint{URL: server.URL, Secret: "s3cret"})
+	dispatcher.Backoff = 100 * time.Millisecond
+
+	var waits []time.Duration
+	dispatcher.sleep = func(d time.Duration) { waits = append(waits, d) }
+
+	event := WebhookEvent{ID: "vest:retry:1", Type: WebhookVest, EmployeeID: "retry"}
+	if err := dispatcher.Dispatch([]WebhookEvent{event}); err != nil {
+		t.Fatalf("Dispatch failed: %v", err)
+	}
+
+	if !slices.Equal(waits, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}) {
+		t.Errorf("Expected doubling backoff, got %v", waits)
+	}
+
+	records, err := deliveryLog.Records()
+	if err != nil {
+		t.Fatalf("Reading delivery log failed: %v", err)
+	}
+	if len(records) != 3 || records[0].Delivered || records[1].Delivered || !records[2].Delivered {
+		t.Errorf("Expected two failed attempts then a delivery, got %+v", records)
+	}
+
+	// A wrong secret is a permanent failure and is not retried
+	dispatcher.Endpoints[0].Secret = "wrong"
+	waits = nil
+	if err := dispatcher.Dispatch([]WebhookEvent{event}); err == nil {
+		t.Error("Expected error for a rejected signature")
+	}
+	if len(waits) != 0 {
+		t.Errorf("Expected no retries after a client error, got %d", len(waits))
+	}
+
+	// Unreachable endpoints use every attempt
+	server.Close()
+	dispatcher.MaxAttempts = 3
+	waits = nil
+	if err := dispatcher.Dispatch([]WebhookEvent{event}); err == nil {
+		t.Error("Expected error for an unreachable endpoint")
+	}
+	if len(waits) != 2 {
+		t.Errorf("Expected 2 retries, got %d", len(waits))
+	}
+}
+
+func TestWebhookDeliveryInBackground(t *testing.T) {
+	release := make(chan struct{})
+	var received sync.WaitGroup
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusServiceUnavailable)
+		received.Done()
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	dispatcher := NewWebhookDispatcher(nil, WebhookEndpoint{URL: server.URL, Secret: "s3cret"})
+	dispatcher.Logger = slog.New(slog.NewJSONHandler(&buf, nil))
+	dispatcher.MaxAttempts = 1
+
+	service := NewVestingService()
+	service.SetLogger(nil)
+	service.SetWebhookDispatcher(dispatcher)
+
+	employee := Employee{
+		ID:         "hook1",
+		StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		TotalUnits: 36000,
+		Schedule:   VestingSchedule{CliffMonths: 12, VestingMonths: 36, VestingType: "linear"},
+	}
+	if err := service.ProcessBatch([]Employee{employee}, time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+
+	// Neither the batch nor the stream waits for the receiver
+	received.Add(2)
+	if err := service.ProcessBatch([]Employee{employee}, time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("ProcessBatch failed: %v", err)
+	}
+	for _, err := range service.StreamBatch(slices.Values([]Employee{employee}), time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		if err != nil {
+			t.Fatalf("StreamBatch failed: %v", err)
+		}
+	}
+	if _, exists := service.GetResult("hook1"); !exists {
+		t.Fatal("Expected the result cached before delivery")
+	}
+
+	close(release)
+	received.Wait()
+	dispatcher.Flush()
+
+	// Failed deliveries are logged
+	logged := strings.Count(buf.String(), "webhook delivery failed")
+	if logged != 2 || !strings.Contains(buf.String(), "unexpected status 503") {
+		t.Errorf("Expected 2 logged delivery failures, got %s", buf.String())
+	}
+}
+
+func TestWebhookObserve(t *testing.T) {
+	service := NewVestingService()
+	linear := VestingSchedule{CliffMonths: 12, VestingMonths: 48, VestingType: "linear"}
+	backfilled := Employee{ID: "backfill", StartDate: date(2021, 1, 1), TotalUnits: 48000, Schedule: linear}
+	waived := Employee{ID: "waived", StartDate: date(2021, 1, 1), TotalUnits: 48000, Schedule: linear,
+		Amendments: []ScheduleAmendment{{date(2021, 6, 1), VestingSchedule{CliffMonths: 0, VestingMonths: 43, VestingType: "linear"}}}}
+
+	tests := []struct {
+		name     string
+		employee Employee
+		runs     []time.Time
+		expected [][]string
+	}{
+		// A backfill run does not become the baseline of the next run
+		{"backfill", backfilled, []time.Time{date(2022, 3, 1), date(2021, 11, 1), date(2022, 4, 1)},
+			[][]string{nil, nil, {WebhookVest}}},
+		// A cliff waived by an amendment is never crossed
+		{"waived_cliff", waived, []time.Time{date(2021, 5, 1), date(2021, 7, 1), date(2022, 2, 1)},
+			[][]string{nil, {WebhookVest}, {WebhookVest}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A dispatcher built without NewWebhookDispatcher works too
+			dispatcher := &WebhookDispatcher{}
+			for i, asOfDate := range tt.runs {
+				result, err := service.calculateVesting(tt.employee, asOfDate)
+				if err != nil {
+					t.Fatalf("calculateVesting failed: %v", err)
+				}
+				var types []string
+				for _, event := range dispatcher.Observe(tt.employee, result) {
+					types = append(types, event.Type)
+				}
+				if !slices.Equal(types, tt.expected[i]) {
+					t.Errorf("%s: expected events %v, got %v", asOfDate.Format("2006-01-02"), tt.expected[i], types)
+				}
+			}
+		})
+	}
+
+	// Retries of a dispatcher literal wait with time.Sleep
+	receiver := &webhookReceiver{secret: "s3cret", failures: 1}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	dispatcher := &WebhookDispatcher{Endpoints: []WebhookEndpoint{{URL: server.URL, Secret: "s3cret"}}, MaxAttempts: 2}
+	if err := dispatcher.Dispatch([]WebhookEvent{{ID: "vest:literal:1", Type: WebhookVest, EmployeeID: "literal"}}); err != nil {
+		t.Errorf("Dispatch failed: %v", err)
+	}
+}